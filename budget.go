@@ -0,0 +1,55 @@
+package wikimediaparser
+
+import (
+  "fmt"
+  "time"
+)
+
+// BudgetError is the error of a parse stopped for exceeding the MaxSteps or
+// Timeout of its ParseOptions
+type BudgetError struct {
+  Limit   string // "steps" or "time"
+  Steps   int
+  Elapsed time.Duration
+}
+
+func (e *BudgetError) Error() string {
+  return fmt.Sprintf("parse budget exceeded (%s): %d steps in %s", e.Limit, e.Steps, e.Elapsed)
+}
+
+// budget bounds the work of a whole parser tree: every token consumed, by the
+// recoveries and the sub parsers too, is a step
+type budget struct {
+  maxSteps int
+  timeout  time.Duration
+  start    time.Time
+  steps    int
+}
+
+// the clock is only read every clockSteps steps
+const clockSteps = 256
+
+func newBudget(opts ParseOptions) *budget {
+  if opts.MaxSteps <= 0 && opts.Timeout <= 0 {
+    return nil
+  }
+  return &budget{maxSteps: opts.MaxSteps, timeout: opts.Timeout, start: time.Now()}
+}
+
+// spend panics with a *BudgetError when the budget is exhausted. The parser
+// recoveries let it through, parseItems turns it into an error.
+func (b *budget) spend(steps int) {
+  if b == nil || steps <= 0 {
+    return
+  }
+  before := b.steps
+  b.steps += steps
+  if b.maxSteps > 0 && b.steps > b.maxSteps {
+    panic(&BudgetError{Limit: "steps", Steps: b.steps, Elapsed: time.Since(b.start)})
+  }
+  if b.timeout > 0 && before/clockSteps != b.steps/clockSteps {
+    if elapsed := time.Since(b.start); elapsed > b.timeout {
+      panic(&BudgetError{Limit: "time", Steps: b.steps, Elapsed: elapsed})
+    }
+  }
+}
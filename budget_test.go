@@ -0,0 +1,55 @@
+package wikimediaparser
+
+import (
+  "strings"
+  "testing"
+  "time"
+)
+
+func TestUnclosedNowiki(t *testing.T) {
+  // used to loop forever on the end of the text
+  _, ds := ParseWithDiagnostics("Intro\n\n<nowiki>{{citation")
+  assertEqual(t, "diagnostics", 1, len(ds))
+  assertEqual(t, "code", "unexpected-eof", ds[0].Code)
+}
+
+func TestMaxSteps(t *testing.T) {
+  source := strings.Repeat("{{citation|citation=[[Amour]] et [http://example.org amitié]}}\n", 20)
+
+  nodes, _, err := ParseChecked(source, ParseOptions{MaxSteps: 100})
+  b, ok := err.(*BudgetError)
+  if !ok {
+    t.Fatalf("Expected a budget error, got %v", err)
+  }
+  assertEqual(t, "limit", "steps", b.Limit)
+  assertEqual(t, "steps", 101, b.Steps)
+  assertEqual(t, "nodes", 0, len(nodes))
+
+  nodes, ds := ParseWithOptions(source, ParseOptions{MaxSteps: 100})
+  assertEqual(t, "nodes", 0, len(nodes))
+  assertEqual(t, "diagnostics", 1, len(ds))
+  assertEqual(t, "code", "budget-exceeded", ds[0].Code)
+  assertEqual(t, "severity", SeverityError, ds[0].Severity)
+
+  nodes, _, err = ParseChecked(source, ParseOptions{MaxSteps: 100000})
+  if err != nil {
+    t.Errorf("Unexpected error %v", err)
+  }
+  assertEqual(t, "nodes", 40, len(nodes))
+}
+
+func TestTimeout(t *testing.T) {
+  source := strings.Repeat("{{citation|citation=[[Amour]] et [http://example.org amitié]}}\n", 20)
+
+  _, _, err := ParseChecked(source, ParseOptions{Timeout: time.Nanosecond})
+  b, ok := err.(*BudgetError)
+  if !ok {
+    t.Fatalf("Expected a budget error, got %v", err)
+  }
+  assertEqual(t, "limit", "time", b.Limit)
+
+  _, _, err = ParseChecked(source, ParseOptions{Timeout: time.Minute})
+  if err != nil {
+    t.Errorf("Unexpected error %v", err)
+  }
+}
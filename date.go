@@ -0,0 +1,71 @@
+package wikimediaparser
+
+import (
+  "fmt"
+  "regexp"
+  "strings"
+  "time"
+)
+
+var frenchMonths = map[string]string{
+  "janvier":   "January",
+  "février":   "February",
+  "fevrier":   "February",
+  "mars":      "March",
+  "avril":     "April",
+  "mai":       "May",
+  "juin":      "June",
+  "juillet":   "July",
+  "août":      "August",
+  "aout":      "August",
+  "septembre": "September",
+  "octobre":   "October",
+  "novembre":  "November",
+  "décembre":  "December",
+  "decembre":  "December",
+}
+
+var dateLayouts = []string{
+  "2006-01-02",
+  "2006-01",
+  "2006",
+  "2 January 2006",
+  "January 2, 2006",
+  "January 2 2006",
+  "January 2006",
+}
+
+var firstOfMonth = regexp.MustCompile(`^1er\b`)
+
+// ParseDate reads the dates found in template parameters: ISO dates, bare
+// years and day month year in English or French ("1er mars 2010").
+func ParseDate(s string) (time.Time, error) {
+  words := strings.Fields(firstOfMonth.ReplaceAllString(strings.TrimSpace(s), "1"))
+  for ix, w := range words {
+    if en, ok := frenchMonths[strings.ToLower(w)]; ok {
+      words[ix] = en
+    }
+  }
+  normalized := strings.Join(words, " ")
+
+  for _, layout := range dateLayouts {
+    if t, err := time.Parse(layout, normalized); err == nil {
+      return t, nil
+    }
+  }
+  return time.Time{}, fmt.Errorf("unable to parse date %q", s)
+}
+
+// MonthNumber returns the month for an English or French month name, 0 if unknown
+func MonthNumber(name string) time.Month {
+  name = strings.ToLower(strings.TrimSpace(name))
+  if en, ok := frenchMonths[name]; ok {
+    name = strings.ToLower(en)
+  }
+  for m := time.January; m <= time.December; m++ {
+    if strings.ToLower(m.String()) == name {
+      return m
+    }
+  }
+  return 0
+}
@@ -0,0 +1,173 @@
+package wikimediaparser
+
+import (
+  "encoding/json"
+  "fmt"
+  "io"
+  "strings"
+  "unicode/utf8"
+)
+
+// Severity orders the diagnostics, from informative notes to errors
+type Severity int
+
+const (
+  SeverityInfo = Severity(iota)
+  SeverityWarning
+  SeverityError
+)
+
+func (s Severity) String() string {
+  switch s {
+  case SeverityInfo:
+    return "info"
+  case SeverityWarning:
+    return "warning"
+  case SeverityError:
+    return "error"
+  }
+  panic(fmt.Sprintf("Unknown severity %d", s))
+}
+
+// ParseSeverity reads a severity name: info, warning or error
+func ParseSeverity(s string) (Severity, error) {
+  for _, sev := range []Severity{SeverityInfo, SeverityWarning, SeverityError} {
+    if sev.String() == strings.ToLower(strings.TrimSpace(s)) {
+      return sev, nil
+    }
+  }
+  return SeverityInfo, fmt.Errorf("unknown severity %q", s)
+}
+
+func (s Severity) MarshalText() ([]byte, error) {
+  return []byte(s.String()), nil
+}
+
+func (s *Severity) UnmarshalText(text []byte) error {
+  sev, err := ParseSeverity(string(text))
+  if err != nil {
+    return err
+  }
+  *s = sev
+  return nil
+}
+
+// Diagnostic is a problem found in a page source
+//    - Code identifies the kind of problem, like unexpected-eof
+//    - Span locates the problem in the source
+//    - Line, Column and Excerpt are only set when the source is known, see Locate
+type Diagnostic struct {
+  Page     string   `json:"page,omitempty"`
+  Code     string   `json:"code"`
+  Message  string   `json:"message"`
+  Severity Severity `json:"severity"`
+  Span     Span     `json:"span"`
+  Line     int      `json:"line,omitempty"`
+  Column   int      `json:"column,omitempty"`
+  Excerpt  string   `json:"excerpt,omitempty"`
+}
+
+func (d Diagnostic) String() string {
+  where := d.Span.String()
+  if d.Line > 0 {
+    where = fmt.Sprintf("%d:%d", d.Line, d.Column)
+  }
+  if d.Page != "" {
+    where = d.Page + ":" + where
+  }
+  return fmt.Sprintf("%s: %s: %s [%s]", where, d.Severity, d.Message, d.Code)
+}
+
+// Locate sets the line, column and excerpt of the diagnostic from the source it was found in
+func (d *Diagnostic) Locate(source string) {
+  d.Line, d.Column = LineColumn(source, d.Span.Start)
+  d.Excerpt = Excerpt(source, d.Span)
+}
+
+// Excerpt renders the source line holding the start of the span, and below it
+// a ^~~ marker under the part of the span on this line. Tabs are kept in the
+// marker line so that the caret stays aligned whatever the tab width.
+func Excerpt(source string, span Span) string {
+  start := span.Start
+  if start > len(source) {
+    start = len(source)
+  }
+  if start < 0 {
+    start = 0
+  }
+  lineStart := strings.LastIndex(source[:start], "\n") + 1
+  lineEnd := strings.Index(source[start:], "\n")
+  if lineEnd < 0 {
+    lineEnd = len(source)
+  } else {
+    lineEnd += start
+  }
+  end := span.End
+  if end > lineEnd {
+    end = lineEnd
+  }
+
+  marker := ""
+  for _, r := range source[lineStart:start] {
+    if r == '\t' {
+      marker += "\t"
+    } else {
+      marker += " "
+    }
+  }
+  marker += "^"
+  if width := utf8.RuneCountInString(source[start:maxInt(start, end)]); width > 1 {
+    marker += strings.Repeat("~", width-1)
+  }
+  return source[lineStart:lineEnd] + "\n" + marker
+}
+
+func maxInt(a, b int) int {
+  if a > b {
+    return a
+  }
+  return b
+}
+
+// Diagnostics is a list of diagnostics, with text and JSON formatters
+type Diagnostics []Diagnostic
+
+// Locate sets the position of every diagnostic in source
+func (ds Diagnostics) Locate(source string) {
+  for ix := range ds {
+    ds[ix].Locate(source)
+  }
+}
+
+// Max returns the highest severity of the list, and false if the list is empty
+func (ds Diagnostics) Max() (Severity, bool) {
+  ret := SeverityInfo
+  for _, d := range ds {
+    if d.Severity > ret {
+      ret = d.Severity
+    }
+  }
+  return ret, len(ds) > 0
+}
+
+// WriteText writes one line per diagnostic, followed by its indented excerpt
+func (ds Diagnostics) WriteText(w io.Writer) error {
+  for _, d := range ds {
+    out := d.String() + "\n"
+    if d.Excerpt != "" {
+      out += "    " + strings.Replace(d.Excerpt, "\n", "\n    ", -1) + "\n"
+    }
+    if _, err := io.WriteString(w, out); err != nil {
+      return err
+    }
+  }
+  return nil
+}
+
+// WriteJSON writes the diagnostics as a JSON array
+func (ds Diagnostics) WriteJSON(w io.Writer) error {
+  if ds == nil {
+    ds = Diagnostics{}
+  }
+  return json.NewEncoder(w).Encode(ds)
+}
@@ -0,0 +1,57 @@
+package wikimediaparser
+
+import (
+  "bytes"
+  "encoding/json"
+  "fmt"
+  "testing"
+)
+
+func TestExcerpt(t *testing.T) {
+  assertEqual(t, "first line", "{{w|Hugo}}\n    ^~~~", Excerpt("{{w|Hugo}}\nsuite", Span{Start: 4, End: 8}))
+  assertEqual(t, "multibyte runes", "é {{x\n  ^~~", Excerpt("é {{x", Span{Start: 3, End: 6}))
+  assertEqual(t, "tabs", "\tx [[c\n\t  ^", Excerpt("avant\n\tx [[c", Span{Start: 9, End: 9}))
+  assertEqual(t, "span on several lines", "ab\n ^", Excerpt("ab\ncd", Span{Start: 1, End: 4}))
+}
+
+func TestParseWithDiagnostics(t *testing.T) {
+  _, ds := ParseWithDiagnostics("Vu [[Victor Hugo]] et {{w|Hugo}}.")
+  assertEqual(t, "no diagnostic", 0, len(ds))
+
+  nodes, ds := ParseWithDiagnostics("Intro\n\n{{citation|Tant va la cruche\n\nSuite")
+  assertEqual(t, "diagnostic count", 1, len(ds))
+  assertEqual(t, "code", "unexpected-eof", ds[0].Code)
+  assertEqual(t, "severity", SeverityError, ds[0].Severity)
+  assertEqual(t, "position", "3:11", fmt.Sprintf("%d:%d", ds[0].Line, ds[0].Column))
+  assertEqual(t, "text", "3:11: error: unexpected end of text, expected \"|\" or \"}}\", section ignored [unexpected-eof]", ds[0].String())
+  assertEqual(t, "invalid node span", nodes[3].Params[0][0].Span, ds[0].Span)
+}
+
+func TestDiagnosticsFormatters(t *testing.T) {
+  ds := Diagnostics{{Page: "Victor Hugo", Code: "unexpected-eof", Message: "unexpected end of text", Severity: SeverityWarning, Span: Span{Start: 2, End: 5}}}
+  ds.Locate("a\n[[c")
+
+  var text bytes.Buffer
+  ds.WriteText(&text)
+  assertEqual(t, "text", "Victor Hugo:2:1: warning: unexpected end of text [unexpected-eof]\n    [[c\n    ^~~\n", text.String())
+
+  var js bytes.Buffer
+  ds.WriteJSON(&js)
+  decoded := Diagnostics{}
+  if err := json.Unmarshal(js.Bytes(), &decoded); err != nil {
+    t.Fatal(err)
+  }
+  assertEqual(t, "json round trip", ds[0], decoded[0])
+
+  js.Reset()
+  Diagnostics(nil).WriteJSON(&js)
+  assertEqual(t, "empty json", "[]\n", js.String())
+
+  max, ok := ds.Max()
+  assertEqual(t, "max", SeverityWarning, max)
+  assertEqual(t, "max found", true, ok)
+
+  sev, err := ParseSeverity("Error")
+  assertEqual(t, "parse severity", SeverityError, sev)
+  assertEqual(t, "parse severity error", nil, err)
+}
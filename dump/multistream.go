@@ -0,0 +1,214 @@
+package dump
+
+import (
+  "bufio"
+  "compress/bzip2"
+  "errors"
+  "fmt"
+  "io"
+  "os"
+  "sort"
+  "strconv"
+  "strings"
+)
+
+// ErrNotFound is returned for the pages missing from the index
+var ErrNotFound = errors.New("page not found")
+
+// IndexEntry is a line of a multistream index: the offset of the bzip2
+// stream holding the page, its ID and its title
+type IndexEntry struct {
+  Offset int64
+  ID     int
+  Title  string
+}
+
+// Index locates the pages in a multistream dump
+type Index struct {
+  Entries []IndexEntry
+  byTitle map[string]int
+  byID    map[int]int
+  offsets []int64
+}
+
+// IndexPath returns the usual name of the index of a multistream dump:
+// xxx-multistream.xml.bz2 goes with xxx-multistream-index.txt.bz2
+func IndexPath(dumpPath string) string {
+  return strings.TrimSuffix(dumpPath, ".xml.bz2") + "-index.txt.bz2"
+}
+
+// OpenIndex reads an index file, decompressing it when its name ends with .bz2
+func OpenIndex(path string) (*Index, error) {
+  fi, err := os.Open(path)
+  if err != nil {
+    return nil, err
+  }
+  defer fi.Close()
+  var r io.Reader = bufio.NewReader(fi)
+  if strings.HasSuffix(path, ".bz2") {
+    r = bzip2.NewReader(r)
+  }
+  return ReadIndex(r)
+}
+
+// ReadIndex reads offset:id:title lines. Titles may contain colons.
+func ReadIndex(r io.Reader) (*Index, error) {
+  ix := &Index{Entries: make([]IndexEntry, 0), byTitle: make(map[string]int), byID: make(map[int]int)}
+  scanner := bufio.NewScanner(r)
+  line := 0
+  for scanner.Scan() {
+    line += 1
+    if scanner.Text() == "" {
+      continue
+    }
+    parts := strings.SplitN(scanner.Text(), ":", 3)
+    if len(parts) != 3 {
+      return nil, fmt.Errorf("index line %d: expected offset:id:title", line)
+    }
+    offset, err := strconv.ParseInt(parts[0], 10, 64)
+    if err != nil {
+      return nil, fmt.Errorf("index line %d: %s", line, err)
+    }
+    id, err := strconv.Atoi(parts[1])
+    if err != nil {
+      return nil, fmt.Errorf("index line %d: %s", line, err)
+    }
+    ix.add(IndexEntry{Offset: offset, ID: id, Title: parts[2]})
+  }
+  if err := scanner.Err(); err != nil {
+    return nil, err
+  }
+  return ix, nil
+}
+
+func (ix *Index) add(e IndexEntry) {
+  ix.byTitle[e.Title] = len(ix.Entries)
+  ix.byID[e.ID] = len(ix.Entries)
+  if len(ix.offsets) == 0 || ix.offsets[len(ix.offsets)-1] != e.Offset {
+    ix.offsets = append(ix.offsets, e.Offset)
+  }
+  ix.Entries = append(ix.Entries, e)
+}
+
+// Lookup returns the index entry of a page title
+func (ix *Index) Lookup(title string) (IndexEntry, bool) {
+  pos, ok := ix.byTitle[title]
+  if !ok {
+    return IndexEntry{}, false
+  }
+  return ix.Entries[pos], true
+}
+
+// LookupID returns the index entry of a page ID
+func (ix *Index) LookupID(id int) (IndexEntry, bool) {
+  pos, ok := ix.byID[id]
+  if !ok {
+    return IndexEntry{}, false
+  }
+  return ix.Entries[pos], true
+}
+
+// Streams returns the offsets of the streams holding pages, in file order
+func (ix *Index) Streams() []int64 {
+  return ix.offsets
+}
+
+// Multistream gives random access to the pages of a multistream dump: each
+// bzip2 stream holds about a hundred pages and can be decompressed alone.
+type Multistream struct {
+  file  *os.File
+  size  int64
+  Index *Index
+}
+
+// OpenMultistream opens a .xml.bz2 multistream dump and reads its index
+func OpenMultistream(dumpPath string, indexPath string) (*Multistream, error) {
+  index, err := OpenIndex(indexPath)
+  if err != nil {
+    return nil, err
+  }
+  fi, err := os.Open(dumpPath)
+  if err != nil {
+    return nil, err
+  }
+  st, err := fi.Stat()
+  if err != nil {
+    fi.Close()
+    return nil, err
+  }
+  return &Multistream{file: fi, size: st.Size(), Index: index}, nil
+}
+
+func (m *Multistream) Close() error {
+  return m.file.Close()
+}
+
+// SiteInfo reads the site info from the header stream, before the first page stream
+func (m *Multistream) SiteInfo() (*SiteInfo, error) {
+  end := m.size
+  if len(m.Index.offsets) > 0 {
+    end = m.Index.offsets[0]
+  }
+  r, err := NewReader(bzip2.NewReader(io.NewSectionReader(m.file, 0, end)))
+  if err != nil {
+    return nil, err
+  }
+  return r.SiteInfo(), nil
+}
+
+// Stream returns a reader on the pages of the stream starting at offset
+func (m *Multistream) Stream(offset int64) (*Reader, error) {
+  end := m.size
+  next := sort.Search(len(m.Index.offsets), func(i int) bool { return m.Index.offsets[i] > offset })
+  if next < len(m.Index.offsets) {
+    end = m.Index.offsets[next]
+  }
+  return newFragmentReader(bzip2.NewReader(bufio.NewReader(io.NewSectionReader(m.file, offset, end-offset))))
+}
+
+// streams returns a reader on the pages of the consecutive streams from to
+// to, excluded, of the index: the bzip2 reader decodes them one after the other
+func (m *Multistream) streams(from int, to int) (*Reader, error) {
+  offset, end := m.Index.offsets[from], m.size
+  if to < len(m.Index.offsets) {
+    end = m.Index.offsets[to]
+  }
+  return newFragmentReader(bzip2.NewReader(bufio.NewReader(io.NewSectionReader(m.file, offset, end-offset))))
+}
+
+// Page decompresses the stream holding the page with the given title
+func (m *Multistream) Page(title string) (*Page, error) {
+  e, ok := m.Index.Lookup(title)
+  if !ok {
+    return nil, ErrNotFound
+  }
+  return m.pageIn(e)
+}
+
+// PageByID decompresses the stream holding the page with the given ID
+func (m *Multistream) PageByID(id int) (*Page, error) {
+  e, ok := m.Index.LookupID(id)
+  if !ok {
+    return nil, ErrNotFound
+  }
+  return m.pageIn(e)
+}
+
+func (m *Multistream) pageIn(e IndexEntry) (*Page, error) {
+  r, err := m.Stream(e.Offset)
+  if err != nil {
+    return nil, err
+  }
+  for {
+    p, err := r.Next()
+    if err == io.EOF {
+      return nil, ErrNotFound
+    }
+    if err != nil {
+      return nil, err
+    }
+    if p.ID == e.ID {
+      return p, nil
+    }
+  }
+}
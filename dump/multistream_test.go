@@ -0,0 +1,174 @@
+package dump
+
+import (
+  "errors"
+  "fmt"
+  "io"
+  "testing"
+)
+
+const (
+  multistreamDump  = "testdata/multistream.xml.bz2"
+  multistreamIndex = "testdata/multistream-index.txt.bz2"
+)
+
+func TestOpenCompressed(t *testing.T) {
+  r, err := Open(multistreamDump)
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer r.Close()
+
+  if r.SiteInfo() == nil || len(r.SiteInfo().Namespaces) != 12 {
+    t.Errorf("Unexpected site info %+v", r.SiteInfo())
+  }
+  ps := readAll(t, r)
+  if len(ps) != 7 || ps[0].Title != "Amour" || ps[6].Title != "Auteur:Alain" {
+    t.Errorf("Unexpected pages %+v", ps)
+  }
+}
+
+func TestIndex(t *testing.T) {
+  if IndexPath("frwikiquote-20140622-pages-articles-multistream.xml.bz2") != "frwikiquote-20140622-pages-articles-multistream-index.txt.bz2" {
+    t.Errorf("Unexpected index path %s", IndexPath("frwikiquote-20140622-pages-articles-multistream.xml.bz2"))
+  }
+
+  ix, err := OpenIndex(multistreamIndex)
+  if err != nil {
+    t.Fatal(err)
+  }
+  if len(ix.Entries) != 7 || len(ix.Streams()) != 3 {
+    t.Errorf("Unexpected index: %d entries, %d streams", len(ix.Entries), len(ix.Streams()))
+  }
+  e, ok := ix.Lookup("Catégorie:Amour")
+  if !ok || e.ID != 13 || e.Offset != ix.Streams()[1] {
+    t.Errorf("Unexpected entry %+v", e)
+  }
+  if _, ok := ix.LookupID(42); ok {
+    t.Errorf("Unexpected entry for a missing ID")
+  }
+}
+
+func TestMultistreamPage(t *testing.T) {
+  m, err := OpenMultistream(multistreamDump, IndexPath(multistreamDump))
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer m.Close()
+
+  si, err := m.SiteInfo()
+  if err != nil || si.DBName != "frwikiquote" {
+    t.Errorf("Unexpected site info %+v (%v)", si, err)
+  }
+
+  for _, title := range []string{"Victor Hugo", "Modèle:Citation", "Auteur:Alain"} {
+    p, err := m.Page(title)
+    if err != nil {
+      t.Fatalf("%s: %s", title, err)
+    }
+    if p.Title != title {
+      t.Errorf("Unexpected page %q for %q", p.Title, title)
+    }
+  }
+
+  p, err := m.PageByID(12)
+  if err != nil || p.Redirect != "Victor Hugo" {
+    t.Errorf("Unexpected page %+v (%v)", p, err)
+  }
+
+  if _, err := m.Page("Absente"); err != ErrNotFound {
+    t.Errorf("Expected ErrNotFound, got %v", err)
+  }
+
+  r, err := m.Stream(m.Index.Streams()[2])
+  if err != nil {
+    t.Fatal(err)
+  }
+  if ps := readAll(t, r); len(ps) != 2 || ps[1].Title != "Auteur:Alain" {
+    t.Errorf("Unexpected last stream %+v", ps)
+  }
+}
+
+func TestEachInOrder(t *testing.T) {
+  m, err := OpenMultistream(multistreamDump, IndexPath(multistreamDump))
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer m.Close()
+
+  for _, workers := range []int{0, 1, 2, 8} {
+    ids := make([]int, 0)
+    err := m.Each(workers, func(p *Page) error {
+      ids = append(ids, p.ID)
+      return nil
+    })
+    if err != nil {
+      t.Fatal(err)
+    }
+    if fmt.Sprint(ids) != "[10 11 12 13 14 15 16]" {
+      t.Errorf("Unexpected pages with %d workers: %v", workers, ids)
+    }
+  }
+
+  stop := errors.New("stop")
+  count := 0
+  err = m.Each(2, func(p *Page) error {
+    count += 1
+    if count == 4 {
+      return stop
+    }
+    return nil
+  })
+  if err != stop || count != 4 {
+    t.Errorf("Unexpected early stop: %v after %d pages", err, count)
+  }
+}
+
+func TestStreamsPerTask(t *testing.T) {
+  for _, c := range []struct{ streams, workers, expected int }{{16, 1, 8}, {16, 2, 4}, {16, 8, 1}, {3, 4, 1}, {50000, 4, maxStreamsPerTask}} {
+    if n := streamsPerTask(c.streams, c.workers); n != c.expected {
+      t.Errorf("Unexpected run for %d streams on %d workers: %d", c.streams, c.workers, n)
+    }
+  }
+}
+
+const (
+  benchDump  = "testdata/bench-multistream.xml.bz2"
+  benchIndex = "testdata/bench-multistream-index.txt.bz2"
+)
+
+func BenchmarkSequentialRead(b *testing.B) {
+  for i := 0; i < b.N; i++ {
+    r, err := Open(benchDump)
+    if err != nil {
+      b.Fatal(err)
+    }
+    for {
+      if _, err := r.Next(); err == io.EOF {
+        break
+      } else if err != nil {
+        b.Fatal(err)
+      }
+    }
+    r.Close()
+  }
+}
+
+func benchmarkEach(b *testing.B, workers int) {
+  m, err := OpenMultistream(benchDump, benchIndex)
+  if err != nil {
+    b.Fatal(err)
+  }
+  defer m.Close()
+  b.ResetTimer()
+  for i := 0; i < b.N; i++ {
+    if err := m.Each(workers, func(p *Page) error { return nil }); err != nil {
+      b.Fatal(err)
+    }
+  }
+}
+
+func BenchmarkParallelRead1(b *testing.B) { benchmarkEach(b, 1) }
+func BenchmarkParallelRead2(b *testing.B) { benchmarkEach(b, 2) }
+func BenchmarkParallelRead4(b *testing.B) { benchmarkEach(b, 4) }
+func BenchmarkParallelRead8(b *testing.B) { benchmarkEach(b, 8) }
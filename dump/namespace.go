@@ -0,0 +1,124 @@
+package dump
+
+import (
+  "fmt"
+  "strconv"
+  "strings"
+)
+
+// canonicalNamespaces are the English names of the namespaces every MediaWiki has
+var canonicalNamespaces = map[string]int{
+  "media":          -2,
+  "special":        -1,
+  "main":           0,
+  "talk":           1,
+  "user":           2,
+  "user talk":      3,
+  "project":        4,
+  "project talk":   5,
+  "file":           6,
+  "file talk":      7,
+  "mediawiki":      8,
+  "mediawiki talk": 9,
+  "template":       10,
+  "template talk":  11,
+  "help":           12,
+  "help talk":      13,
+  "category":       14,
+  "category talk":  15,
+}
+
+func normalizeNamespace(name string) string {
+  return strings.ToLower(strings.Join(strings.Fields(strings.Replace(name, "_", " ", -1)), " "))
+}
+
+// NamespaceKey resolves a namespace given by number, canonical English name
+// (Main, Template, Category...) or local name of the site info (Modèle,
+// Catégorie...). The site info may be nil.
+func (si *SiteInfo) NamespaceKey(name string) (int, bool) {
+  if key, err := strconv.Atoi(strings.TrimSpace(name)); err == nil {
+    return key, true
+  }
+  norm := normalizeNamespace(name)
+  if key, ok := canonicalNamespaces[norm]; ok {
+    return key, true
+  }
+  if si == nil {
+    return 0, false
+  }
+  for _, ns := range si.Namespaces {
+    if normalizeNamespace(ns.Name) == norm {
+      return ns.Key, true
+    }
+  }
+  return 0, false
+}
+
+// NamespaceName resolves the namespace prefix of a title, Catégorie in
+// [[Catégorie:Amour]], by canonical English or local name. The name returned
+// is the local one of the site info, the canonical one without site info.
+// Numbers and the main namespace are not prefixes: [[Main:Page]] is a page
+// of the main namespace.
+func (si *SiteInfo) NamespaceName(prefix string) (string, bool) {
+  norm := normalizeNamespace(prefix)
+  key, ok := canonicalNamespaces[norm]
+  if si != nil {
+    for _, ns := range si.Namespaces {
+      if ok && ns.Key == key || !ok && normalizeNamespace(ns.Name) == norm {
+        if ns.Key == 0 || ns.Name == "" {
+          return "", false
+        }
+        return ns.Name, true
+      }
+    }
+  }
+  if !ok || key == 0 {
+    return "", false
+  }
+  return strings.ToUpper(norm[:1]) + norm[1:], true
+}
+
+// NamespaceFilter selects pages by namespace number
+type NamespaceFilter struct {
+  include map[int]bool
+  exclude map[int]bool
+}
+
+// NewNamespaceFilter builds a filter keeping the pages of the included
+// namespaces, all when include is empty, minus the excluded ones. Names are
+// resolved with SiteInfo.NamespaceKey.
+func NewNamespaceFilter(si *SiteInfo, include []string, exclude []string) (*NamespaceFilter, error) {
+  f := &NamespaceFilter{include: make(map[int]bool), exclude: make(map[int]bool)}
+  for _, set := range []struct {
+    names []string
+    keys  map[int]bool
+  }{{include, f.include}, {exclude, f.exclude}} {
+    for _, name := range set.names {
+      key, ok := si.NamespaceKey(name)
+      if !ok {
+        return nil, fmt.Errorf("unknown namespace %q", name)
+      }
+      set.keys[key] = true
+    }
+  }
+  return f, nil
+}
+
+// ParseNamespaceList splits a comma separated list of namespaces, as given on command lines
+func ParseNamespaceList(s string) []string {
+  ret := make([]string, 0)
+  for _, name := range strings.Split(s, ",") {
+    if strings.TrimSpace(name) != "" {
+      ret = append(ret, strings.TrimSpace(name))
+    }
+  }
+  return ret
+}
+
+// Match reports whether pages of namespace ns are kept
+func (f *NamespaceFilter) Match(ns int) bool {
+  if len(f.include) > 0 && !f.include[ns] {
+    return false
+  }
+  return !f.exclude[ns]
+}
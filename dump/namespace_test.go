@@ -0,0 +1,91 @@
+package dump
+
+import (
+  "testing"
+)
+
+func TestNamespaceKey(t *testing.T) {
+  r, err := Open(multistreamDump)
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer r.Close()
+  si := r.SiteInfo()
+
+  for name, expected := range map[string]int{"10": 10, "Template": 10, "Modèle": 10, "modèle": 10, "Main": 0, "Category talk": 15, "Auteur": 102, "-1": -1} {
+    key, ok := si.NamespaceKey(name)
+    if !ok || key != expected {
+      t.Errorf("Unexpected key for %q: %d, %v", name, key, ok)
+    }
+  }
+  if _, ok := si.NamespaceKey("Inconnu"); ok {
+    t.Errorf("Unexpected key for an unknown namespace")
+  }
+
+  var none *SiteInfo
+  if key, ok := none.NamespaceKey("Category"); !ok || key != 14 {
+    t.Errorf("Unexpected key without site info: %d, %v", key, ok)
+  }
+  if _, ok := none.NamespaceKey("Catégorie"); ok {
+    t.Errorf("Local names need the site info")
+  }
+}
+
+func TestNamespaceName(t *testing.T) {
+  r, err := Open(multistreamDump)
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer r.Close()
+  si := r.SiteInfo()
+
+  for prefix, expected := range map[string]string{"Catégorie": "Catégorie", "category": "Catégorie", "auteur": "Auteur", "Project": "Wikiquote", "Template_talk": "Template talk"} {
+    name, ok := si.NamespaceName(prefix)
+    if !ok || name != expected {
+      t.Errorf("Unexpected name for %q: %q, %v", prefix, name, ok)
+    }
+  }
+  for _, prefix := range []string{"Star Wars", "w", "Main", "14", ""} {
+    if name, ok := si.NamespaceName(prefix); ok {
+      t.Errorf("Unexpected namespace %q for %q", name, prefix)
+    }
+  }
+
+  var none *SiteInfo
+  if name, ok := none.NamespaceName("category talk"); !ok || name != "Category talk" {
+    t.Errorf("Unexpected name without site info: %q, %v", name, ok)
+  }
+  if _, ok := none.NamespaceName("Catégorie"); ok {
+    t.Errorf("Local names need the site info")
+  }
+}
+
+func TestNamespaceFilter(t *testing.T) {
+  r, err := Open(multistreamDump)
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer r.Close()
+
+  f, err := NewNamespaceFilter(r.SiteInfo(), nil, ParseNamespaceList("Modèle, Category,MediaWiki,Help"))
+  if err != nil {
+    t.Fatal(err)
+  }
+  titles := make([]string, 0)
+  for _, p := range readAll(t, r) {
+    if f.Match(p.NS) {
+      titles = append(titles, p.Title)
+    }
+  }
+  if len(titles) != 5 || titles[3] != "Amitié" || titles[4] != "Auteur:Alain" {
+    t.Errorf("Unexpected pages %v", titles)
+  }
+
+  f, _ = NewNamespaceFilter(nil, []string{"Main"}, nil)
+  if !f.Match(0) || f.Match(102) {
+    t.Errorf("Unexpected include filter")
+  }
+  if _, err := NewNamespaceFilter(nil, []string{"Modèle"}, nil); err == nil {
+    t.Errorf("Expected an error for an unknown namespace")
+  }
+}
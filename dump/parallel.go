@@ -0,0 +1,109 @@
+package dump
+
+import (
+  "io"
+  "runtime"
+)
+
+// maxStreamsPerTask bounds the streams a goroutine decodes in a row. Each
+// bzip2 reader allocates a 3.6MB block buffer, only reused for the following
+// streams it decodes: a reader per stream allocated four times as much as a
+// sequential reading of the dump. Longer runs hold more pages in memory.
+const maxStreamsPerTask = 8
+
+// streamsPerTask gives at least two runs of streams to every worker, for
+// the decoding to stay balanced on the small dumps
+func streamsPerTask(streams int, workers int) int {
+  n := streams / (workers * 2)
+  if n < 1 {
+    return 1
+  }
+  if n > maxStreamsPerTask {
+    return maxStreamsPerTask
+  }
+  return n
+}
+
+type streamResult struct {
+  pages []*Page
+  err   error
+}
+
+// Each decodes the page streams of the dump on workers goroutines, one per
+// CPU when workers is below 1, and calls f with the pages in dump order. It
+// stops at the first decoding error or error returned by f. Each goroutine
+// decodes a run of consecutive streams with a single bzip2 reader.
+func (m *Multistream) Each(workers int, f func(p *Page) error) error {
+  if workers < 1 {
+    workers = runtime.NumCPU()
+  }
+
+  // results holds the runs of streams decoded or being decoded, in dump
+  // order: its capacity bounds the number of runs decoded ahead of f.
+  // decoders holds a token per run being decoded.
+  results := make(chan chan streamResult, workers)
+  decoders := make(chan struct{}, workers)
+  done := make(chan struct{})
+  defer close(done)
+
+  go func() {
+    defer close(results)
+    streams := len(m.Index.Streams())
+    run := streamsPerTask(streams, workers)
+    for from := 0; from < streams; from += run {
+      to := from + run
+      if to > streams {
+        to = streams
+      }
+      select {
+      case decoders <- struct{}{}:
+      case <-done:
+        return
+      }
+      c := make(chan streamResult, 1)
+      select {
+      case results <- c:
+      case <-done:
+        <-decoders
+        return
+      }
+      go func(from int, to int) {
+        pages, err := m.streamPages(from, to)
+        <-decoders
+        c <- streamResult{pages, err}
+      }(from, to)
+    }
+  }()
+
+  for c := range results {
+    r := <-c
+    if r.err != nil {
+      return r.err
+    }
+    for _, p := range r.pages {
+      if err := f(p); err != nil {
+        return err
+      }
+    }
+  }
+  return nil
+}
+
+// streamPages decodes the pages of the streams from to to, excluded, of the index
+func (m *Multistream) streamPages(from int, to int) ([]*Page, error) {
+  r, err := m.streams(from, to)
+  if err != nil {
+    return nil, err
+  }
+  ret := make([]*Page, 0)
+  for {
+    p, err := r.Next()
+    if err == io.EOF {
+      return ret, nil
+    }
+    if err != nil {
+      return nil, err
+    }
+    ret = append(ret, p)
+  }
+}
@@ -0,0 +1,239 @@
+// Package dump reads MediaWiki XML dumps one page at a time.
+//
+// The reader is built on the encoding/xml token decoder: only the page being
+// decoded is held in memory, whatever the size of the dump.
+//
+//  r, err := dump.NewReader(f)
+//  for {
+//    page, err := r.Next()
+//    if err == io.EOF {
+//      break
+//    }
+//    ...
+//  }
+package dump
+
+import (
+  "bufio"
+  "compress/bzip2"
+  "encoding/xml"
+  "io"
+  "os"
+  "strings"
+  "time"
+)
+
+// Namespace is a <namespace> of the site info, Name is empty for the main namespace
+type Namespace struct {
+  Key  int    `xml:"key,attr"`
+  Case string `xml:"case,attr"`
+  Name string `xml:",chardata"`
+}
+
+// SiteInfo is the <siteinfo> header of the dump
+type SiteInfo struct {
+  SiteName   string      `xml:"sitename"`
+  DBName     string      `xml:"dbname"`
+  Base       string      `xml:"base"`
+  Generator  string      `xml:"generator"`
+  Case       string      `xml:"case"`
+  Namespaces []Namespace `xml:"namespaces>namespace"`
+}
+
+// Contributor is the author of a revision, Username and ID for registered users, IP otherwise
+type Contributor struct {
+  Username string `xml:"username"`
+  ID       int    `xml:"id"`
+  IP       string `xml:"ip"`
+}
+
+type Revision struct {
+  ID          int         `xml:"id"`
+  ParentID    int         `xml:"parentid"`
+  Timestamp   time.Time   `xml:"timestamp"`
+  Contributor Contributor `xml:"contributor"`
+  Comment     string      `xml:"comment"`
+  Text        string      `xml:"text"`
+  SHA1        string      `xml:"sha1"`
+}
+
+// Page is a <page> of the dump. Redirect is the target title of redirection
+// pages. Revision is the last revision of the page: the history dumps list
+// all of them, the others only the current one.
+type Page struct {
+  ID       int
+  NS       int
+  Title    string
+  Redirect string
+  Revision Revision
+}
+
+// Reader decodes the pages of a dump
+//    - closer is the file opened by Open
+//    - fragment is set when reading a single stream of a multistream dump, see newFragmentReader
+//    - depth counts the elements opened around the pages, the fragments end when it is back to 0
+type Reader struct {
+  dec      *xml.Decoder
+  siteInfo *SiteInfo
+  pending  *xml.StartElement
+  closer   io.Closer
+  fragment bool
+  depth    int
+}
+
+// Open opens a dump file, decompressing it on the fly when its name ends with .bz2
+func Open(path string) (*Reader, error) {
+  fi, err := os.Open(path)
+  if err != nil {
+    return nil, err
+  }
+  var r io.Reader = bufio.NewReader(fi)
+  if strings.HasSuffix(path, ".bz2") {
+    r = bzip2.NewReader(r)
+  }
+  ret, err := NewReader(r)
+  if err != nil {
+    fi.Close()
+    return nil, err
+  }
+  ret.closer = fi
+  return ret, nil
+}
+
+// Close closes the file opened by Open, it does nothing for the readers of NewReader
+func (r *Reader) Close() error {
+  if r.closer == nil {
+    return nil
+  }
+  return r.closer.Close()
+}
+
+// NewReader reads the dump header, up to the site info or the first page
+func NewReader(r io.Reader) (*Reader, error) {
+  return newReader(r, false)
+}
+
+// newFragmentReader reads a single stream of a multistream dump. The stream
+// does not hold the whole <mediawiki> element: it is wrapped in one of its
+// own, which the last stream closes before the wrapper does.
+func newFragmentReader(r io.Reader) (*Reader, error) {
+  return newReader(io.MultiReader(strings.NewReader("<mediawiki>"), r, strings.NewReader("</mediawiki>")), true)
+}
+
+func newReader(r io.Reader, fragment bool) (*Reader, error) {
+  ret := &Reader{dec: xml.NewDecoder(r), fragment: fragment}
+  for {
+    se, err := ret.nextStart()
+    if err == io.EOF {
+      return ret, nil
+    }
+    if err != nil {
+      return nil, err
+    }
+    switch se.Name.Local {
+    case "siteinfo":
+      ret.siteInfo = &SiteInfo{}
+      if err := ret.dec.DecodeElement(ret.siteInfo, &se); err != nil {
+        return nil, err
+      }
+      ret.depth -= 1
+      return ret, nil
+    case "page":
+      ret.pending = &se
+      return ret, nil
+    }
+  }
+}
+
+// SiteInfo returns the site info of the dump, nil when the dump has none
+func (r *Reader) SiteInfo() *SiteInfo {
+  return r.siteInfo
+}
+
+// Next decodes the next page. It returns io.EOF after the last page.
+func (r *Reader) Next() (*Page, error) {
+  for {
+    var se xml.StartElement
+    if r.pending != nil {
+      se = *r.pending
+      r.pending = nil
+    } else {
+      var err error
+      if se, err = r.nextStart(); err != nil {
+        return nil, err
+      }
+    }
+    if se.Name.Local != "page" {
+      continue
+    }
+
+    p := &Page{}
+    if err := r.decodePage(p); err != nil {
+      return nil, err
+    }
+    r.depth -= 1
+    return p, nil
+  }
+}
+
+// decodePage reads the children of a <page> up to its end element. The
+// revisions are decoded one after the other into p.Revision, so that the
+// history of a page is never held in memory.
+func (r *Reader) decodePage(p *Page) error {
+  for {
+    t, err := r.dec.Token()
+    if err != nil {
+      return err
+    }
+    switch t := t.(type) {
+    case xml.EndElement:
+      // the children are consumed whole, this is </page>
+      return nil
+    case xml.StartElement:
+      switch t.Name.Local {
+      case "id":
+        err = r.dec.DecodeElement(&p.ID, &t)
+      case "ns":
+        err = r.dec.DecodeElement(&p.NS, &t)
+      case "title":
+        err = r.dec.DecodeElement(&p.Title, &t)
+      case "redirect":
+        for _, a := range t.Attr {
+          if a.Name.Local == "title" {
+            p.Redirect = a.Value
+          }
+        }
+        err = r.dec.Skip()
+      case "revision":
+        p.Revision = Revision{}
+        err = r.dec.DecodeElement(&p.Revision, &t)
+      default:
+        err = r.dec.Skip()
+      }
+      if err != nil {
+        return err
+      }
+    }
+  }
+}
+
+// nextStart skips the tokens up to the next start element, counted as open
+// until its caller consumes it
+func (r *Reader) nextStart() (xml.StartElement, error) {
+  for {
+    t, err := r.dec.Token()
+    if err != nil {
+      return xml.StartElement{}, err
+    }
+    switch t := t.(type) {
+    case xml.StartElement:
+      r.depth += 1
+      return t.Copy(), nil
+    case xml.EndElement:
+      r.depth -= 1
+      if r.fragment && r.depth == 0 {
+        return xml.StartElement{}, io.EOF
+      }
+    }
+  }
+}
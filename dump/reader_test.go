@@ -0,0 +1,172 @@
+package dump
+
+import (
+  "io"
+  "os"
+  "strings"
+  "testing"
+)
+
+const header = `<mediawiki xmlns="http://www.mediawiki.org/xml/export-0.8/" version="0.8" xml:lang="fr">
+  <siteinfo>
+    <sitename>Wikiquote</sitename>
+    <dbname>frwikiquote</dbname>
+    <base>http://fr.wikiquote.org/wiki/Wikiquote:Accueil</base>
+    <generator>MediaWiki 1.24wmf9</generator>
+    <case>first-letter</case>
+    <namespaces>
+      <namespace key="-2" case="first-letter">Média</namespace>
+      <namespace key="0" case="first-letter" />
+      <namespace key="14" case="first-letter">Catégorie</namespace>
+    </namespaces>
+  </siteinfo>
+`
+
+const pages = `  <page>
+    <title>Hugo</title>
+    <ns>0</ns>
+    <id>12</id>
+    <redirect title="Victor Hugo" />
+    <revision>
+      <id>100</id>
+      <timestamp>2014-05-31T06:17:54Z</timestamp>
+      <contributor>
+        <ip>127.0.0.1</ip>
+      </contributor>
+      <text xml:space="preserve">#REDIRECTION [[Victor Hugo]]</text>
+      <sha1>abc</sha1>
+    </revision>
+  </page>
+  <page>
+    <title>Catégorie:Amour</title>
+    <ns>14</ns>
+    <id>13</id>
+    <revision>
+      <id>200</id>
+      <parentid>199</parentid>
+      <timestamp>2014-06-01T10:00:00Z</timestamp>
+      <contributor>
+        <username>Motisse38</username>
+        <id>19858</id>
+      </contributor>
+      <comment>catégorie</comment>
+      <text xml:space="preserve">{{citation|&lt;poem&gt;Un&lt;/poem&gt;}}</text>
+    </revision>
+  </page>
+</mediawiki>`
+
+func readAll(t *testing.T, r *Reader) []*Page {
+  ret := make([]*Page, 0)
+  for {
+    p, err := r.Next()
+    if err == io.EOF {
+      return ret
+    }
+    if err != nil {
+      t.Fatal(err)
+    }
+    ret = append(ret, p)
+  }
+}
+
+func TestReader(t *testing.T) {
+  r, err := NewReader(strings.NewReader(header + pages))
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  si := r.SiteInfo()
+  if si == nil || si.DBName != "frwikiquote" || len(si.Namespaces) != 3 {
+    t.Fatalf("Unexpected site info %+v", si)
+  }
+  if ns := si.Namespaces[2]; ns.Key != 14 || ns.Name != "Catégorie" {
+    t.Errorf("Unexpected namespace %+v", ns)
+  }
+
+  ps := readAll(t, r)
+  if len(ps) != 2 {
+    t.Fatalf("Unexpected page count %d", len(ps))
+  }
+  if ps[0].Redirect != "Victor Hugo" || ps[0].Revision.Contributor.IP != "127.0.0.1" || ps[0].Revision.SHA1 != "abc" {
+    t.Errorf("Unexpected redirect page %+v", ps[0])
+  }
+  p := ps[1]
+  if p.ID != 13 || p.NS != 14 || p.Title != "Catégorie:Amour" || p.Redirect != "" {
+    t.Errorf("Unexpected page %+v", p)
+  }
+  rev := p.Revision
+  if rev.ID != 200 || rev.ParentID != 199 || rev.Comment != "catégorie" || rev.Contributor.Username != "Motisse38" || rev.Contributor.ID != 19858 {
+    t.Errorf("Unexpected revision %+v", rev)
+  }
+  if rev.Timestamp.Format("2006-01-02 15:04") != "2014-06-01 10:00" {
+    t.Errorf("Unexpected timestamp %s", rev.Timestamp)
+  }
+  if rev.Text != "{{citation|<poem>Un</poem>}}" {
+    t.Errorf("Unexpected text %q", rev.Text)
+  }
+}
+
+func TestReaderWithoutSiteInfo(t *testing.T) {
+  fi, err := os.Open("../sample.xml")
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer fi.Close()
+
+  r, err := NewReader(fi)
+  if err != nil {
+    t.Fatal(err)
+  }
+  if r.SiteInfo() != nil {
+    t.Errorf("Unexpected site info %+v", r.SiteInfo())
+  }
+  ps := readAll(t, r)
+  if len(ps) != 1 || ps[0].Title != "Amour" || ps[0].ID != 1865 || !strings.HasPrefix(ps[0].Revision.Text, "{{en cours.CM}}") {
+    t.Errorf("Unexpected pages %+v", ps)
+  }
+}
+
+func TestReaderHistory(t *testing.T) {
+  history := `<mediawiki>
+  <page>
+    <title>Amour</title>
+    <ns>0</ns>
+    <id>1865</id>
+    <revision>
+      <id>1</id>
+      <comment>création</comment>
+      <text xml:space="preserve">v1</text>
+    </revision>
+    <upload><filename>x.png</filename></upload>
+    <revision>
+      <id>2</id>
+      <parentid>1</parentid>
+      <text xml:space="preserve">v2</text>
+    </revision>
+  </page>
+</mediawiki>`
+  r, err := NewReader(strings.NewReader(history))
+  if err != nil {
+    t.Fatal(err)
+  }
+  ps := readAll(t, r)
+  if len(ps) != 1 || ps[0].ID != 1865 {
+    t.Fatalf("Unexpected pages %+v", ps)
+  }
+  if rev := ps[0].Revision; rev.ID != 2 || rev.ParentID != 1 || rev.Comment != "" || rev.Text != "v2" {
+    t.Errorf("Expected the last revision only, got %+v", rev)
+  }
+}
+
+func TestFragmentReader(t *testing.T) {
+  middle := strings.TrimSuffix(pages, "</mediawiki>")
+  for name, stream := range map[string]string{"middle stream": middle, "last stream": pages} {
+    r, err := newFragmentReader(strings.NewReader(stream))
+    if err != nil {
+      t.Fatal(err)
+    }
+    if ps := readAll(t, r); len(ps) != 2 || ps[1].ID != 13 {
+      t.Errorf("Unexpected pages in the %s %+v", name, ps)
+    }
+  }
+}
@@ -0,0 +1,342 @@
+package wikimediaparser
+
+import (
+  "fmt"
+  "strings"
+)
+
+// EqualOptions tune the comparison of Equal and Diff
+//    - IgnoreSpans compares nodes parsed from different sources
+//    - IgnoreWhitespace merges consecutive text nodes, folds blanks and drops blank text nodes
+type EqualOptions struct {
+  IgnoreSpans      bool
+  IgnoreWhitespace bool
+}
+
+// Difference is a single difference found by Diff, located by its path in the first tree
+// (or in the second one for added nodes)
+type Difference struct {
+  Path    Path
+  Message string
+}
+
+func (d Difference) String() string {
+  return fmt.Sprintf("%s: %s", d.Path, d.Message)
+}
+
+// Differences is the result of Diff, empty when the trees are equal
+type Differences []Difference
+
+func (ds Differences) String() string {
+  lines := make([]string, 0, len(ds))
+  for _, d := range ds {
+    lines = append(lines, d.String())
+  }
+  return strings.Join(lines, "\n")
+}
+
+// Equal reports whether the two trees have the same structure and content
+func Equal(a, b Nodes, opts EqualOptions) bool {
+  return len(Diff(a, b, opts)) == 0
+}
+
+// Diff compares two trees. Node lists are aligned on their longest common
+// subsequence so that an insertion does not shift every following node, and
+// replaced nodes of the same type are compared parameter by parameter.
+// Consecutive text nodes are compared as one, the paths counting them as a
+// single node.
+func Diff(a, b Nodes, opts EqualOptions) Differences {
+  if opts.IgnoreWhitespace {
+    a = normalizeWhitespace(a)
+    b = normalizeWhitespace(b)
+  }
+  d := &differ{opts: opts, diffs: make(Differences, 0)}
+  d.nodes(a, b, nil, Step{Param: -1})
+  return d.diffs
+}
+
+// normalizeWhitespace merges the text runs split by the tokenizer and drops the blank ones
+func normalizeWhitespace(ns Nodes) Nodes {
+  ret := make(Nodes, 0, len(ns))
+  for _, n := range mergeText(ns) {
+    if n.Typ == NodeText {
+      n.Val = strings.Join(strings.Fields(n.Val), " ")
+      if n.Val == "" {
+        continue
+      }
+    }
+    if n.NamedParams != nil {
+      named := make(map[string]Nodes, len(n.NamedParams))
+      for k, v := range n.NamedParams {
+        named[k] = normalizeWhitespace(v)
+      }
+      n.NamedParams = named
+    }
+    if n.Params != nil {
+      params := make([]Nodes, 0, len(n.Params))
+      for _, v := range n.Params {
+        params = append(params, normalizeWhitespace(v))
+      }
+      n.Params = params
+    }
+    ret = append(ret, n)
+  }
+  return ret
+}
+
+type differ struct {
+  opts  EqualOptions
+  diffs Differences
+}
+
+func (d *differ) add(path Path, format string, params ...interface{}) {
+  d.diffs = append(d.diffs, Difference{Path: path, Message: fmt.Sprintf(format, params...)})
+}
+
+// fingerprint serializes a node with everything the comparison looks at
+func (d *differ) fingerprint(n *Node) string {
+  out := fmt.Sprintf("%d:%q", n.Typ, n.Val)
+  if !d.opts.IgnoreSpans {
+    out += "@" + n.Span.String()
+  }
+  for _, k := range n.namedKeys() {
+    out += fmt.Sprintf(" %q=[", k)
+    for ix := range n.NamedParams[k] {
+      out += d.fingerprint(&n.NamedParams[k][ix]) + ","
+    }
+    out += "]"
+  }
+  for _, p := range n.Params {
+    out += " |["
+    for ix := range p {
+      out += d.fingerprint(&p[ix]) + ","
+    }
+    out += "]"
+  }
+  return out
+}
+
+func (d *differ) nodes(a, b Nodes, path Path, s Step) {
+  a, b = mergeText(a), mergeText(b)
+  // the fingerprints are numbered so that the alignment compares ints
+  ids := make(map[string]int)
+  number := func(ns Nodes) []int {
+    ret := make([]int, len(ns))
+    for ix := range ns {
+      f := d.fingerprint(&ns[ix])
+      if _, ok := ids[f]; !ok {
+        ids[f] = len(ids)
+      }
+      ret[ix] = ids[f]
+    }
+    return ret
+  }
+  fa, fb := number(a), number(b)
+
+  removed := make([]int, 0)
+  added := make([]int, 0)
+  i, j := 0, 0
+  for _, common := range commonSubsequence(fa, fb) {
+    for ; i < common[0]; i++ {
+      removed = append(removed, i)
+    }
+    for ; j < common[1]; j++ {
+      added = append(added, j)
+    }
+    d.changes(a, b, removed, added, path, s)
+    removed, added = removed[:0], added[:0]
+    i, j = i+1, j+1
+  }
+  for ; i < len(a); i++ {
+    removed = append(removed, i)
+  }
+  for ; j < len(b); j++ {
+    added = append(added, j)
+  }
+  d.changes(a, b, removed, added, path, s)
+}
+
+// changes reports a run of removed and added nodes between two common nodes,
+// pairing them when their types match
+func (d *differ) changes(a, b Nodes, removed []int, added []int, path Path, s Step) {
+  for len(removed) > 0 && len(added) > 0 && a[removed[0]].Typ == b[added[0]].Typ {
+    s.Index = removed[0]
+    d.node(&a[removed[0]], &b[added[0]], path.child(s))
+    removed, added = removed[1:], added[1:]
+  }
+  for _, ix := range removed {
+    s.Index = ix
+    d.add(path.child(s), "removed %s", summary(&a[ix]))
+  }
+  for _, ix := range added {
+    s.Index = ix
+    d.add(path.child(s), "added %s", summary(&b[ix]))
+  }
+}
+
+func (d *differ) node(a, b *Node, path Path) {
+  if a.Val != b.Val {
+    d.add(path, "%q != %q", a.Val, b.Val)
+  }
+  if !d.opts.IgnoreSpans && a.Span != b.Span {
+    d.add(path, "span %s != %s", a.Span, b.Span)
+  }
+
+  for _, k := range a.namedKeys() {
+    if _, ok := b.NamedParams[k]; !ok {
+      d.add(path.child(Step{Named: k, Param: -1, Index: -1}), "removed parameter %s", summaryNodes(a.NamedParams[k]))
+      continue
+    }
+    d.nodes(a.NamedParams[k], b.NamedParams[k], path, Step{Named: k, Param: -1})
+  }
+  for _, k := range b.namedKeys() {
+    if _, ok := a.NamedParams[k]; !ok {
+      d.add(path.child(Step{Named: k, Param: -1, Index: -1}), "added parameter %s", summaryNodes(b.NamedParams[k]))
+    }
+  }
+
+  for ix := 0; ix < len(a.Params) || ix < len(b.Params); ix++ {
+    switch {
+    case ix >= len(b.Params):
+      d.add(path.child(Step{Param: ix, Index: -1}), "removed parameter %s", summaryNodes(a.Params[ix]))
+    case ix >= len(a.Params):
+      d.add(path.child(Step{Param: ix, Index: -1}), "added parameter %s", summaryNodes(b.Params[ix]))
+    default:
+      d.nodes(a.Params[ix], b.Params[ix], path, Step{Param: ix})
+    }
+  }
+}
+
+// summary describes a node in a wikitext like form
+func summary(n *Node) string {
+  switch n.Typ {
+  case NodeText, NodeInvalid, NodeUnknown:
+    return fmt.Sprintf("%s %q", strings.TrimSpace(n.Typ.String()), n.Val)
+  case NodeTemplate:
+    return fmt.Sprintf("{{%s}}", strings.TrimSpace(n.StringParam("name")))
+  case NodeLink:
+    return fmt.Sprintf("[[%s]]", n.StringParamOrEmpty("link"))
+  case NodeELink:
+    return fmt.Sprintf("[%s]", n.StringParamOrEmpty("link"))
+  case NodeTitle:
+    return fmt.Sprintf("title %q", strings.TrimSpace(n.NamedParams["title"].StringRepresentation()))
+  }
+  return strings.TrimSpace(n.Typ.String())
+}
+
+func summaryNodes(ns Nodes) string {
+  parts := make([]string, 0, len(ns))
+  for ix := range ns {
+    parts = append(parts, summary(&ns[ix]))
+  }
+  return "[" + strings.Join(parts, ", ") + "]"
+}
+
+// commonSubsequence returns the index pairs of a longest common subsequence
+// of a and b. It uses the linear space variant of Myers' O(ND) algorithm:
+// the lists are cut at the middle of an optimal edit path, found by running
+// the search from both ends, and the halves are aligned recursively.
+func commonSubsequence(a, b []int) [][2]int {
+  ret := make([][2]int, 0)
+  var align func(a0, a1, b0, b1 int)
+  align = func(a0, a1, b0, b1 int) {
+    for a0 < a1 && b0 < b1 && a[a0] == b[b0] {
+      ret = append(ret, [2]int{a0, b0})
+      a0, b0 = a0+1, b0+1
+    }
+    suffix := 0
+    for a1-suffix > a0 && b1-suffix > b0 && a[a1-suffix-1] == b[b1-suffix-1] {
+      suffix += 1
+    }
+    a1, b1 = a1-suffix, b1-suffix
+
+    if a0 < a1 && b0 < b1 {
+      if x, y, ok := middleSnake(a[a0:a1], b[b0:b1]); ok {
+        align(a0, a0+x, b0, b0+y)
+        align(a0+x, a1, b0+y, b1)
+      }
+    }
+    for ix := 0; ix < suffix; ix++ {
+      ret = append(ret, [2]int{a1 + ix, b1 + ix})
+    }
+  }
+  align(0, len(a), 0, len(b))
+  return ret
+}
+
+// middleSnake returns the point where the forward and the reverse searches of
+// a shortest edit path between a and b meet, false when they have nothing in
+// common
+func middleSnake(a, b []int) (int, int, bool) {
+  n, m := len(a), len(b)
+  maxD := (n + m + 1) / 2
+  offset := maxD
+  // forward[offset+k] is the furthest x reached on the diagonal k = x - y,
+  // backward the same from the end of the lists
+  forward := make([]int, 2*maxD+2)
+  backward := make([]int, 2*maxD+2)
+  for ix := range forward {
+    forward[ix], backward[ix] = -1, -1
+  }
+  forward[offset+1], backward[offset+1] = 0, 0
+  delta := n - m
+  // the paths meet on the forward search when delta is odd
+  odd := delta%2 != 0
+  // diagonals leaving the lists on the right or on the bottom
+  fStart, fEnd, bStart, bEnd := 0, 0, 0, 0
+
+  for d := 0; d < maxD; d++ {
+    for k := -d + fStart; k <= d-fEnd; k += 2 {
+      ix := offset + k
+      var x int
+      if k == -d || k != d && forward[ix-1] < forward[ix+1] {
+        x = forward[ix+1]
+      } else {
+        x = forward[ix-1] + 1
+      }
+      y := x - k
+      for x < n && y < m && a[x] == b[y] {
+        x, y = x+1, y+1
+      }
+      forward[ix] = x
+      switch {
+      case x > n:
+        fEnd += 2
+      case y > m:
+        fStart += 2
+      case odd:
+        bx := offset + delta - k
+        if bx >= 0 && bx < len(backward) && backward[bx] != -1 && x >= n-backward[bx] {
+          return x, y, true
+        }
+      }
+    }
+
+    for k := -d + bStart; k <= d-bEnd; k += 2 {
+      ix := offset + k
+      var x int
+      if k == -d || k != d && backward[ix-1] < backward[ix+1] {
+        x = backward[ix+1]
+      } else {
+        x = backward[ix-1] + 1
+      }
+      y := x - k
+      for x < n && y < m && a[n-x-1] == b[m-y-1] {
+        x, y = x+1, y+1
+      }
+      backward[ix] = x
+      switch {
+      case x > n:
+        bEnd += 2
+      case y > m:
+        bStart += 2
+      case !odd:
+        fx := offset + delta - k
+        if fx >= 0 && fx < len(forward) && forward[fx] != -1 && forward[fx] >= n-x {
+          return forward[fx], offset + forward[fx] - fx, true
+        }
+      }
+    }
+  }
+  return 0, 0, false
+}
@@ -0,0 +1,53 @@
+package wikimediaparser
+
+import (
+  "testing"
+)
+
+func TestNodesEqual(t *testing.T) {
+  a := Parse(Tokenize("{{citation|Tant va la cruche}}\n[[Victor Hugo]]"))
+  b := Parse(Tokenize("{{citation| Tant  va la cruche }}\n\n[[Victor Hugo]]"))
+
+  assertEqual(t, "same source", true, Equal(a, Parse(Tokenize("{{citation|Tant va la cruche}}\n[[Victor Hugo]]")), EqualOptions{}))
+  assertEqual(t, "whitespace", false, Equal(a, b, EqualOptions{IgnoreSpans: true}))
+  assertEqual(t, "spans", false, Equal(a, b, EqualOptions{IgnoreWhitespace: true}))
+  assertEqual(t, "spans and whitespace", true, Equal(a, b, EqualOptions{IgnoreSpans: true, IgnoreWhitespace: true}))
+}
+
+func TestNodesEqualHandBuilt(t *testing.T) {
+  expected := Nodes{
+    {Typ: NodeTitle, NamedParams: map[string]Nodes{
+      "level": {{Typ: NodeText, Val: "2"}},
+      "title": {{Typ: NodeText, Val: "Citations"}},
+    }},
+  }
+  provided := Parse(Tokenize("== Citations ==\n"))
+  assertEqual(t, "diff", "", Diff(expected, provided, EqualOptions{IgnoreSpans: true, IgnoreWhitespace: true}).String())
+}
+
+func TestDiff(t *testing.T) {
+  opts := EqualOptions{IgnoreSpans: true, IgnoreWhitespace: true}
+  a := Parse(Tokenize("{{Réf Livre|titre=Les Misérables|auteur=[[Victor Hugo]]}}"))
+  b := Parse(Tokenize("{{Réf Livre|titre=Notre-Dame de Paris|éditeur=Gallimard|auteur=[[Victor Hugo]]}}"))
+  assertEqual(t, "changed parameters",
+    "/0/titre/0: \"Les Misérables\" != \"Notre-Dame de Paris\"\n/0/éditeur: added parameter [Text \"Gallimard\"]",
+    Diff(a, b, opts).String())
+
+  a = Parse(Tokenize("Un\n{{citation|deux}}\n[[trois]]"))
+  b = Parse(Tokenize("Un\n[[trois]]\nquatre"))
+  assertEqual(t, "removed and added nodes",
+    "/1: removed {{citation}}\n/2: added Text \"quatre\"",
+    Diff(a, b, opts).String())
+
+  a = Parse(Tokenize("{{w|Hugo}}"))
+  b = Parse(Tokenize("{{w|Hugo|fr}}"))
+  assertEqual(t, "positional parameter", "/0/#1: added parameter [Text \"fr\"]", Diff(a, b, opts).String())
+
+  a = Parse(Tokenize("Tant va la cruche à l'eau {{w|Hugo}}"))
+  b = Parse(Tokenize("Tant va la cruche à la rivière {{w|Hugo}}"))
+  assertEqual(t, "merged text", "/0: \"Tant va la cruche à l'eau \" != \"Tant va la cruche à la rivière \"", Diff(a, b, EqualOptions{IgnoreSpans: true}).String())
+
+  a = Parse(Tokenize("{{w|Hugo}}"))
+  b = Parse(Tokenize(" {{w|Hugo}}"))
+  assertEqual(t, "span", "/0: span 0-10 != 1-11\n/0/name/0: span 2-3 != 3-4\n/0/#0/0: span 4-8 != 5-9", Diff(a, b, EqualOptions{IgnoreWhitespace: true}).String())
+}
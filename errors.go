@@ -2,8 +2,8 @@ package wikimediaparser
 
 import (
   "fmt"
-  "github.com/golang/glog"
-  "math"
+  "runtime"
+  "strings"
 )
 
 const (
@@ -28,161 +28,129 @@ type inspectable struct {
   parser   *parser
   pos      int
   message  string
-  delta    int // how many token to dump
-  start    int
-  err      interface{}
   behavior behaviorOnError
   class    inspectableClass
 }
 
+func (me inspectable) String() string {
+  return fmt.Sprintf("%s: %s", me.class.String(), me.message)
+}
+
 func (p *parser) defaultInspectable(i *inspectable) {
   i.parser = p
   i.pos = p.pos
-  i.delta = 8
   i.behavior = p.onError
   i.class = RuntimeException
 }
 
-func createInspectable(p *parser, pos int, m string, e interface{}) inspectable {
-  is := inspectable{}
-  p.defaultInspectable(&is)
-  is.pos = pos - 1
-  is.message = m
-  is.start = p.pos
-  is.err = e
+func outOfBoundsPanic(p *parser, s int) {
+  myerr := inspectable{}
+  p.defaultInspectable(&myerr)
+  myerr.message = fmt.Sprintf("unexpected end of text, expected %s", p.expected())
+  myerr.class = EOFException
+  myerr.behavior = ignoreSectionBehavior
 
-  return is
+  p.log("Out of bounds Panic (from %d : %s)", s, p.items[s:])
+  panic(myerr)
 }
 
-func (me inspectable) handle() {
-  rL := report
-  if me.behavior == ignoreSectionBehavior {
-    rL = noReport
-  }
-
-  switch me.err.(type) {
-  case inspectable:
-    rL = me.err.(inspectable).handleError(rL)
-  }
-  if rL == report {
-    me.handleError(rL)
-  }
+// eofPanic reports a construct left open at the end of the text
+func eofPanic(p *parser, expected string) {
+  myerr := inspectable{}
+  p.defaultInspectable(&myerr)
+  myerr.message = fmt.Sprintf("unexpected end of text, expected %q", expected)
+  myerr.class = EOFException
+  panic(myerr)
 }
 
-func (me inspectable) handleError(parentRL reportLevel) reportLevel {
-  rl := parentRL
-  if me.behavior != abortBehavior {
-    rl = noReport
-  } else {
-    glog.V(2).Infof("Parent Report Level: %s, and me: %s, and parser: %s", parentRL.String(), me.behavior.String(), me.parser.onError.String())
+// expected describes the tokens that would have closed the parser
+func (p *parser) expected() string {
+  parts := make([]string, 0)
+  for _, t := range p.exitTypes {
+    parts = append(parts, fmt.Sprintf("%q", tokenText(t)))
   }
-  _, ok := me.err.(inspectable)
-  if !ok && rl == report {
-    if me.err != nil {
-      glog.V(2).Infof("Original error was: %+v", me.err)
+  if len(p.exitSequence) > 0 {
+    seq := ""
+    for _, t := range p.exitSequence {
+      seq += tokenText(t)
     }
-    me.dumpStream()
+    parts = append(parts, fmt.Sprintf("%q", seq))
   }
-  return rl
+  return strings.Join(parts, " or ")
 }
 
-type reportLevel int
-
-const (
-  noReport = reportLevel(iota)
-  report
-)
-
-func (rl reportLevel) String() string {
-  switch rl {
-  case noReport:
-    return "No report"
-  case report:
-    return "Report"
+func tokenText(t token) string {
+  if t == tokenLF {
+    return lf
   }
-  panic("Unknown report level")
+  return t.String()
 }
 
-func (me *inspectable) dumpStream() {
-  glog.V(2).Infoln("Inspecting stack during Inspectable error")
-  // rewind in stream
-  // TOKENSTOKENS
-  //   ˆ
-  //   Error position
-  // Start from:
-  // max ( error position - delta, 0)
-  // highlight at:
-  // min ( me.delta, error position)
-  me.parser.pos = int(math.Max(float64(me.pos-me.delta), 0))
-  me.parser.inspectHilight(me.delta*2, int(math.Min(float64(me.delta), float64(me.pos)-1)))
-}
-
-func outOfBoundsPanic(p *parser, s int) {
-  myerr := inspectable{}
-  p.defaultInspectable(&myerr)
-  msg := fmt.Sprintf("Out of bounds Panic (from %d : %s)", s, p.items[s:])
-  myerr.message = msg
-  myerr.class = EOFException
-  myerr.behavior = ignoreSectionBehavior
-
-  p.log(msg)
-  panic(myerr)
+// skippedSpan covers the source ignored by the error recovery
+func (p *parser) skippedSpan() Span {
+  if len(p.items) == 0 {
+    return Span{}
+  }
+  return Span{Start: p.items[0].Pos, End: p.currentItem().Pos}
 }
 
-func (p *parser) syntaxEError(err interface{}, pos int, format string, params ...interface{}) {
-  delta := 4
-  p.pos = int(math.Max(0, float64(pos-delta)))
-  p.log("Syntax error at %q (%d):", p.items[pos].String(), p.pos)
-  p.log(format, params...)
-  p.inspectHilight(delta*2, delta)
-  panic(err)
+// diagnose records the error that made the parser skip the source covered by span
+func (p *parser) diagnose(err interface{}, span Span, skipped string) {
+  d := Diagnostic{Severity: SeverityError, Span: span}
+  switch e := err.(type) {
+  case inspectable:
+    d.Code = "syntax-error"
+    if e.class == EOFException {
+      d.Code = "unexpected-eof"
+    }
+    d.Message = e.message
+  case runtime.Error:
+    d.Code = "internal-error"
+    d.Message = e.Error()
+  case string:
+    d.Code = "syntax-error"
+    d.Message = strings.Replace(e, "\n", ", ", -1)
+  default:
+    d.Code = "parse-error"
+    d.Message = fmt.Sprintf("%v", e)
+  }
+  d.Message += ", " + skipped + " ignored"
+  p.forget(span.End)
+  *p.diagnostics = append(*p.diagnostics, d)
 }
 
-func (p *parser) inspectHilight(ahead int, hi int) {
-  p.log("Error Context:\n")
-  prefix := " "
-  line := "\""
-
-  if p.pos > len(p.items) {
-    p.log("Cannot inspect from here: at end of stream (pos=%d)", p.pos)
-  } else {
-    for pos, content := range p.items[p.pos:] {
-      if pos > ahead {
-        break
-      }
-      if pos == hi {
-        prefix += "ˆ"
-      } else {
-        prefix += " " // * len(content.String())
-      }
-      line = line + content.String()
+// forget drops the diagnostics found by this parser after the resume
+// position: this text will be parsed again
+func (p *parser) forget(resume int) {
+  kept := (*p.diagnostics)[:p.mark]
+  for _, d := range (*p.diagnostics)[p.mark:] {
+    if d.Span.Start < resume {
+      kept = append(kept, d)
     }
   }
-  glog.V(2).Infoln(line + "\"")
-  glog.V(2).Infoln(prefix)
-
+  *p.diagnostics = kept
 }
 
-func (p *parser) inspect(ahead int) {
-  p.inspectHilight(ahead, -1)
+// warn records a problem the parser did not have to recover from
+func (p *parser) warn(code string, span Span, format string, params ...interface{}) {
+  *p.diagnostics = append(*p.diagnostics, Diagnostic{Code: code, Message: fmt.Sprintf(format, params...), Severity: SeverityWarning, Span: span})
 }
 
 // called by main parser or subparser when something wrong appears
 func (p *parser) handleParseError(err interface{}, ret Nodes) Nodes {
-  p.log(">> Error, environment: %s", p.EnvironmentString())
+  p.log(">> Error, environment: %s", p.environment())
+  if b, ok := err.(*BudgetError); ok {
+    // no recovery: the whole parse is over
+    panic(b)
+  }
 
   currentParser := p
   // Ramp up in the parser until we are at top level OR we have someone who want to handle this mess
   for currentParser != nil && currentParser.onError != abortBehavior {
-    p.log("%s", currentParser.onError.String())
+    p.log("%s", currentParser.onError)
     currentParser = currentParser.parent
   }
 
-  if glog.V(9) {
-    p.inspect(5)
-    panic("Error")
-  }
-
   behavior := p.onError
 
   switch behavior {
@@ -199,7 +167,8 @@ func (p *parser) handleParseError(err interface{}, ret Nodes) Nodes {
     p.log("Now at position %d\n", p.pos)
     ret = make([]Node, 0)
 
-    ret = append(ret, Node{Typ: NodeInvalid, Val: fmt.Sprintf("> (ignored until %d )<", p.pos)})
+    ret = append(ret, Node{Typ: NodeInvalid, Val: fmt.Sprintf("> (ignored until %d )<", p.pos), Span: p.skippedSpan()})
+    p.diagnose(err, p.skippedSpan(), "line")
     return ret
 
   case ignoreSectionBehavior:
@@ -213,7 +182,8 @@ func (p *parser) handleParseError(err interface{}, ret Nodes) Nodes {
     p.log("Now at position %d\n", p.pos)
     ret = make([]Node, 0)
 
-    ret = append(ret, Node{Typ: NodeInvalid, Val: fmt.Sprintf("> (ignored until %d )<", p.pos)})
+    ret = append(ret, Node{Typ: NodeInvalid, Val: fmt.Sprintf("> (ignored until %d )<", p.pos), Span: p.skippedSpan()})
+    p.diagnose(err, p.skippedSpan(), "section")
     return ret
   }
   // Go down in the error hierarchy
@@ -0,0 +1,581 @@
+package wikimediaparser
+
+import (
+  "bytes"
+  "fmt"
+  "html"
+  "net/url"
+  "regexp"
+  "strconv"
+  "strings"
+)
+
+// HTMLTemplateRenderer renders a template node through the HTMLWriter
+type HTMLTemplateRenderer func(w *HTMLWriter, n *Node)
+
+// HTMLRenderer turns Nodes into HTML that is safe to embed.
+//    - LinkURL maps an internal link target to its URL, an empty URL renders the label only
+//    - Templates maps canonical template names to their renderer, unknown templates are skipped
+//    - AllowedTags lists the raw HTML tags kept from the source, without their attributes
+//
+// Text is always escaped, external links only keep web and mail URLs, and
+// <poem> blocks become <div class="poem"> with their line breaks preserved.
+// The allowed tags are balanced: a closing tag without opener is dropped and
+// the tags left open are closed at the end of their line or template.
+type HTMLRenderer struct {
+  LinkURL     func(target string) string
+  Templates   map[string]HTMLTemplateRenderer
+  AllowedTags map[string]bool
+}
+
+// NewHTMLRenderer returns a renderer linking to /wiki/ and knowing the common wikiquote templates
+func NewHTMLRenderer() *HTMLRenderer {
+  h := &HTMLRenderer{
+    LinkURL:   defaultLinkURL,
+    Templates: make(map[string]HTMLTemplateRenderer),
+    AllowedTags: map[string]bool{
+      "b": true, "i": true, "u": true, "s": true, "em": true, "strong": true,
+      "small": true, "big": true, "sub": true, "sup": true, "br": true,
+      "blockquote": true, "code": true,
+    },
+  }
+  h.Register("w", htmlWikipediaLink)
+  h.Register("lang", htmlLang)
+  h.Register("date", htmlDate)
+  h.Register("citation", htmlCitation)
+  h.Register("tab", htmlRaw("&emsp;"))
+  h.Register("!", htmlRaw("|"))
+  return h
+}
+
+var defaultHTMLRenderer = NewHTMLRenderer()
+
+// RenderHTML renders the nodes with the default HTMLRenderer
+func RenderHTML(ns Nodes) string {
+  return defaultHTMLRenderer.Render(ns)
+}
+
+func defaultLinkURL(target string) string {
+  return "/wiki/" + url.PathEscape(strings.Replace(target, " ", "_", -1))
+}
+
+// Register sets the renderer for a template name
+func (h *HTMLRenderer) Register(name string, fn HTMLTemplateRenderer) {
+  h.Templates[CanonicalTemplateName(name)] = fn
+}
+
+// Render renders a whole page: headings, lists and paragraphs are built line by line
+func (h *HTMLRenderer) Render(ns Nodes) string {
+  w := &HTMLWriter{h: h}
+  for ix, line := range splitLines(ns) {
+    if w.poem {
+      if ix > 0 {
+        w.newline()
+      }
+      w.Inline(line)
+      continue
+    }
+    w.closeEmphasis()
+
+    if len(line) == 1 && line[0].Typ == NodeTitle {
+      w.closeBlocks()
+      level, _ := strconv.Atoi(line[0].StringParamOrEmpty("level"))
+      if level < 1 {
+        level = 1
+      } else if level > 6 {
+        level = 6
+      }
+      w.write(fmt.Sprintf("<h%d>", level))
+      w.Inline(trimNodes(line[0].NamedParams["title"]))
+      w.closeEmphasis()
+      w.write(fmt.Sprintf("</h%d>\n", level))
+      continue
+    }
+
+    line = mergeText(line)
+    lead := ""
+    if len(line) > 0 && line[0].Typ == NodeText {
+      lead = line[0].Val
+    }
+
+    switch prefix := listPrefix(lead); {
+    case len(line) == 0 || len(line) == 1 && line[0].Typ == NodeText && strings.TrimSpace(lead) == "":
+      w.closeBlocks()
+    case prefix != "":
+      w.closePara()
+      w.setList(prefix)
+      line[0].Val = strings.TrimLeft(lead[len(prefix):], " ")
+      w.Inline(line)
+    case containsPoem(line):
+      // a poem is a block of its own, it can not sit in a paragraph
+      w.closeBlocks()
+      w.Inline(line)
+    default:
+      w.setList("")
+      if w.para {
+        w.write("\n")
+      } else {
+        w.write("<p>")
+        w.para = true
+      }
+      w.Inline(line)
+    }
+  }
+  w.closeBlocks()
+  return w.buf.String()
+}
+
+// splitLines cuts the top-level nodes at line feeds, titles being lines of their own
+func splitLines(ns Nodes) []Nodes {
+  lines := make([]Nodes, 0)
+  current := make(Nodes, 0)
+  for _, n := range ns {
+    switch {
+    case n.Typ == NodeText && n.Val == "\n":
+      lines = append(lines, current)
+      current = make(Nodes, 0)
+    case n.Typ == NodeTitle:
+      if len(current) > 0 {
+        lines = append(lines, current)
+      }
+      lines = append(lines, Nodes{n})
+      current = make(Nodes, 0)
+    default:
+      current = append(current, n)
+    }
+  }
+  if len(current) > 0 {
+    lines = append(lines, current)
+  }
+  return lines
+}
+
+// mergeText joins consecutive text nodes so that markup split by the tokenizer can be scanned
+func mergeText(ns Nodes) Nodes {
+  ret := make(Nodes, 0, len(ns))
+  for _, n := range ns {
+    if n.Typ == NodeText && len(ret) > 0 && ret[len(ret)-1].Typ == NodeText {
+      ret[len(ret)-1].Val += n.Val
+      ret[len(ret)-1].Span.End = n.Span.End
+      continue
+    }
+    ret = append(ret, n)
+  }
+  return ret
+}
+
+func containsPoem(ns Nodes) bool {
+  for _, n := range Flatten(ns) {
+    if n.Typ == NodeText && strings.Contains(strings.ToLower(n.Val), "<poem") {
+      return true
+    }
+  }
+  return false
+}
+
+func listPrefix(s string) string {
+  end := 0
+  for end < len(s) && strings.IndexByte("*#:;", s[end]) >= 0 {
+    end += 1
+  }
+  return s[:end]
+}
+
+// HTMLWriter accumulates the output of an HTMLRenderer and tracks the open elements
+type HTMLWriter struct {
+  h           *HTMLRenderer
+  buf         bytes.Buffer
+  emphasis    []string
+  tags        []openTag
+  floor       int
+  lists       string
+  para        bool
+  poem        bool
+  poemContent bool
+  breaks      int
+}
+
+func (w *HTMLWriter) write(s string) {
+  w.buf.WriteString(s)
+}
+
+// Raw writes trusted HTML
+func (w *HTMLWriter) Raw(s string) {
+  w.flushBreaks()
+  w.write(s)
+}
+
+// Text writes escaped text
+func (w *HTMLWriter) Text(s string) {
+  w.flushBreaks()
+  w.write(html.EscapeString(s))
+}
+
+// Inline renders nodes without block structure
+func (w *HTMLWriter) Inline(ns Nodes) {
+  for _, n := range mergeText(ns) {
+    w.node(&n)
+  }
+}
+
+// Param renders the positional parameter ix of a node
+func (w *HTMLWriter) Param(n *Node, ix int) {
+  if ix >= 0 && ix < len(n.Params) {
+    w.Inline(trimNodes(n.Params[ix]))
+  }
+}
+
+// Named renders the first existing named parameter among keys
+func (w *HTMLWriter) Named(n *Node, keys ...string) bool {
+  for _, k := range keys {
+    if v, ok := n.NamedParams[k]; ok {
+      w.Inline(trimNodes(v))
+      return true
+    }
+  }
+  return false
+}
+
+// trimNodes drops the blank text nodes surrounding a parameter
+func trimNodes(ns Nodes) Nodes {
+  blank := func(n Node) bool {
+    return n.Typ == NodeText && strings.TrimSpace(n.Val) == ""
+  }
+  for len(ns) > 0 && blank(ns[0]) {
+    ns = ns[1:]
+  }
+  for len(ns) > 0 && blank(ns[len(ns)-1]) {
+    ns = ns[:len(ns)-1]
+  }
+  return ns
+}
+
+func (w *HTMLWriter) node(n *Node) {
+  switch n.Typ {
+  case NodeText:
+    w.text(n.Val)
+  case NodeLink:
+    target := strings.TrimSpace(n.StringParamOrEmpty("link"))
+    label := n.NamedParams["link"]
+    if len(n.Params) > 0 {
+      label = n.Params[len(n.Params)-1]
+    }
+    w.anchor(w.h.LinkURL(target), "", label)
+  case NodeELink:
+    target := strings.TrimSpace(n.StringParamOrEmpty("link"))
+    label := n.NamedParams["link"]
+    if len(n.Params) > 0 {
+      label = n.Params[0]
+    }
+    if !safeURL(target) {
+      target = ""
+    }
+    w.anchor(target, ` rel="nofollow"`, label)
+  case NodeTitle:
+    w.Inline(n.NamedParams["title"])
+  case NodeTemplate:
+    if fn, ok := w.h.Templates[CanonicalTemplateName(n.StringParam("name"))]; ok {
+      fn(w, n)
+    }
+  }
+}
+
+func (w *HTMLWriter) anchor(href string, attrs string, label Nodes) {
+  if href == "" {
+    w.Inline(label)
+    return
+  }
+  w.Wrap(fmt.Sprintf(`<a href="%s"%s>`, html.EscapeString(href), attrs), "</a>", func() {
+    w.Inline(label)
+  })
+}
+
+// Wrap writes the trusted open and close tags around fn output, closing the
+// emphasis and the raw tags opened inside
+func (w *HTMLWriter) Wrap(open string, close string, fn func()) {
+  w.Raw(open)
+  depth, floor := len(w.emphasis), w.floor
+  w.floor = len(w.tags)
+  fn()
+  w.closeTags(w.floor)
+  w.floor = floor
+  for len(w.emphasis) > depth {
+    w.toggle(w.emphasis[len(w.emphasis)-1])
+  }
+  w.write(close)
+}
+
+func safeURL(s string) bool {
+  if strings.HasPrefix(s, "//") {
+    return true
+  }
+  u, err := url.Parse(s)
+  if err != nil {
+    return false
+  }
+  switch strings.ToLower(u.Scheme) {
+  case "http", "https", "ftp", "mailto":
+    return true
+  }
+  return false
+}
+
+var inlineMarkup = regexp.MustCompile(`'{2,}|<!--.*?-->|</?[a-zA-Z][a-zA-Z0-9]*(\s[^<>]*)?/?>|\n`)
+var tagMarkup = regexp.MustCompile(`^<(/?)([a-zA-Z][a-zA-Z0-9]*)`)
+
+// text scans raw wikitext for emphasis, HTML tags and line feeds
+func (w *HTMLWriter) text(s string) {
+  last := 0
+  for _, loc := range inlineMarkup.FindAllStringIndex(s, -1) {
+    w.plain(s[last:loc[0]])
+    last = loc[1]
+    m := s[loc[0]:loc[1]]
+
+    switch {
+    case m == "\n":
+      w.newline()
+    case m[0] == '\'':
+      w.apostrophes(len(m))
+    case strings.HasPrefix(m, "<!--"):
+    default:
+      w.tag(m)
+    }
+  }
+  w.plain(s[last:])
+}
+
+func (w *HTMLWriter) plain(s string) {
+  if s == "" {
+    return
+  }
+  s = html.UnescapeString(s)
+  if w.poem && strings.TrimSpace(s) != "" {
+    w.poemContent = true
+  }
+  w.Text(s)
+}
+
+// apostrophes handles '' (italic), ''' (bold) and ''''' (both), extra apostrophes are text
+func (w *HTMLWriter) apostrophes(count int) {
+  switch {
+  case count == 2:
+    w.toggle("i")
+  case count == 3:
+    w.toggle("b")
+  case count == 4:
+    w.plain("'")
+    w.toggle("b")
+  default:
+    w.plain(strings.Repeat("'", count-5))
+    w.toggle("b")
+    w.toggle("i")
+  }
+}
+
+// toggle opens or closes an emphasis tag, reopening the tags opened after it
+func (w *HTMLWriter) toggle(tag string) {
+  for ix := len(w.emphasis) - 1; ix >= 0; ix-- {
+    if w.emphasis[ix] != tag {
+      continue
+    }
+    // the raw tags opened inside the emphasis end with it
+    for len(w.tags) > w.floor && w.tags[len(w.tags)-1].emphasis > ix {
+      w.popTag()
+    }
+    above := append([]string{}, w.emphasis[ix+1:]...)
+    for j := len(w.emphasis) - 1; j >= ix; j-- {
+      w.write("</" + w.emphasis[j] + ">")
+    }
+    w.emphasis = append(w.emphasis[:ix], above...)
+    for _, t := range above {
+      w.write("<" + t + ">")
+    }
+    return
+  }
+  w.Raw("<" + tag + ">")
+  w.emphasis = append(w.emphasis, tag)
+}
+
+func (w *HTMLWriter) closeEmphasis() {
+  w.closeTags(w.floor)
+  for len(w.emphasis) > 0 {
+    w.toggle(w.emphasis[len(w.emphasis)-1])
+  }
+}
+
+func (w *HTMLWriter) tag(m string) {
+  parts := tagMarkup.FindStringSubmatch(m)
+  closing := parts[1] == "/"
+  name := strings.ToLower(parts[2])
+
+  switch {
+  case name == "poem" && !closing && !w.poem:
+    w.closeEmphasis()
+    w.closePara()
+    w.write(`<div class="poem">`)
+    w.poem = true
+    w.poemContent = false
+  case name == "poem" && closing && w.poem:
+    w.closeEmphasis()
+    w.breaks = 0
+    w.write("</div>")
+    w.poem = false
+  case name == "br" && w.h.AllowedTags[name]:
+    w.Raw("<br />")
+  case w.h.AllowedTags[name] && closing:
+    // a closing tag without opener is dropped
+    for ix := len(w.tags) - 1; ix >= w.floor; ix-- {
+      if w.tags[ix].name == name {
+        w.closeTags(ix)
+        break
+      }
+    }
+  case w.h.AllowedTags[name]:
+    w.Raw("<" + name + ">")
+    w.tags = append(w.tags, openTag{name: name, emphasis: len(w.emphasis)})
+  default:
+    w.Text(m)
+  }
+}
+
+// openTag is an allowed raw tag waiting for its closing tag
+type openTag struct {
+  name     string
+  emphasis int
+}
+
+// closeTags closes the raw tags opened above depth
+func (w *HTMLWriter) closeTags(depth int) {
+  for len(w.tags) > depth {
+    w.popTag()
+  }
+}
+
+// popTag closes the last raw tag and the emphasis opened inside it
+func (w *HTMLWriter) popTag() {
+  t := w.tags[len(w.tags)-1]
+  w.tags = w.tags[:len(w.tags)-1]
+  for len(w.emphasis) > t.emphasis {
+    w.toggle(w.emphasis[len(w.emphasis)-1])
+  }
+  w.write("</" + t.name + ">")
+}
+
+// newline ends a line: emphasis is closed and poems keep their line breaks
+func (w *HTMLWriter) newline() {
+  w.closeEmphasis()
+  if !w.poem {
+    w.write("\n")
+  } else if w.poemContent {
+    w.breaks += 1
+  }
+}
+
+func (w *HTMLWriter) flushBreaks() {
+  for ; w.breaks > 0; w.breaks-- {
+    w.write("<br />\n")
+  }
+}
+
+func (w *HTMLWriter) closePara() {
+  if w.para {
+    w.write("</p>\n")
+    w.para = false
+  }
+}
+
+func (w *HTMLWriter) closeBlocks() {
+  w.closeEmphasis()
+  if w.poem {
+    w.breaks = 0
+    w.write("</div>\n")
+    w.poem = false
+  }
+  w.setList("")
+  w.closePara()
+}
+
+var listTags = map[byte][2]string{
+  '*': {"ul", "li"},
+  '#': {"ol", "li"},
+  ':': {"dl", "dd"},
+  ';': {"dl", "dt"},
+}
+
+// setList opens and closes the nested lists to match the line prefix
+func (w *HTMLWriter) setList(prefix string) {
+  common := 0
+  for common < len(prefix) && common < len(w.lists) && listTags[prefix[common]][0] == listTags[w.lists[common]][0] {
+    common += 1
+  }
+  for ix := len(w.lists) - 1; ix >= common; ix-- {
+    tags := listTags[w.lists[ix]]
+    w.write("</" + tags[1] + "></" + tags[0] + ">\n")
+  }
+  if common > 0 && common == len(prefix) {
+    w.write("</" + listTags[w.lists[common-1]][1] + ">\n<" + listTags[prefix[common-1]][1] + ">")
+  }
+  for ix := common; ix < len(prefix); ix++ {
+    tags := listTags[prefix[ix]]
+    w.write("<" + tags[0] + ">\n<" + tags[1] + ">")
+  }
+  w.lists = prefix
+}
+
+func htmlRaw(s string) HTMLTemplateRenderer {
+  return func(w *HTMLWriter, n *Node) {
+    w.Raw(s)
+  }
+}
+
+// {{w|Victor Hugo}} or {{w|Victor Hugo|Hugo}}
+func htmlWikipediaLink(w *HTMLWriter, n *Node) {
+  if len(n.Params) == 0 {
+    return
+  }
+  label := n.Params[0]
+  if len(n.Params) > 1 {
+    label = n.Params[1]
+  }
+  w.anchor(w.h.LinkURL("w:"+strings.TrimSpace(n.Params[0].StringRepresentation())), "", trimNodes(label))
+}
+
+var langCode = regexp.MustCompile(`^[a-zA-Z]{2,8}(-[a-zA-Z0-9]{1,8})*$`)
+
+// {{lang|en|text}} or {{lang|en|texte=text}}
+func htmlLang(w *HTMLWriter, n *Node) {
+  code := ""
+  if len(n.Params) > 0 {
+    code = strings.TrimSpace(n.Params[0].StringRepresentation())
+  }
+  text := func() {
+    if !w.Named(n, "texte", "text") {
+      w.Param(n, 1)
+    }
+  }
+  if langCode.MatchString(code) {
+    w.Wrap(fmt.Sprintf(`<span lang="%s">`, code), "</span>", text)
+  } else {
+    text()
+  }
+}
+
+// {{date|1|mars|2010}}
+func htmlDate(w *HTMLWriter, n *Node) {
+  w.Text(defaultTextRenderer.RenderNode(n))
+}
+
+// {{citation|citation=text}} or {{citation|text}}, a block when it holds a poem
+func htmlCitation(w *HTMLWriter, n *Node) {
+  text, ok := n.NamedParams["citation"]
+  if !ok && len(n.Params) > 0 {
+    text = n.Params[0]
+  }
+  open, close := `<span class="citation">`, "</span>"
+  if containsPoem(text) {
+    open, close = `<div class="citation">`, "</div>"
+  }
+  w.Wrap(open, close, func() {
+    w.Inline(trimNodes(text))
+  })
+}
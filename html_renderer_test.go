@@ -0,0 +1,47 @@
+package wikimediaparser
+
+import (
+  "testing"
+)
+
+func TestHTMLInline(t *testing.T) {
+  cases := map[string]string{
+    "Il ''faut'' '''aimer''' la vie":         "<p>Il <i>faut</i> <b>aimer</b> la vie</p>\n",
+    "[[Victor Hugo|Hugo]]":                   "<p><a href=\"/wiki/Victor_Hugo\">Hugo</a></p>\n",
+    "[http://url.com/ un site]":              "<p><a href=\"http://url.com/\" rel=\"nofollow\">un site</a></p>\n",
+    "[javascript:alert(1) clic]":             "<p>clic</p>\n",
+    "<script>alert(1)</script> & co":         "<p>&lt;script&gt;alert(1)&lt;/script&gt; &amp; co</p>\n",
+    "a<br />b<small>c</small>":               "<p>a<br />b<small>c</small></p>\n",
+    "<span style=\"x\">t</span>":             "<p>&lt;span style=&#34;x&#34;&gt;t&lt;/span&gt;</p>\n",
+    "{{inconnu|x}}{{lang|en|''Hello''}}":     "<p><span lang=\"en\"><i>Hello</i></span></p>\n",
+    "''ouvert [[a|b]]":                       "<p><i>ouvert <a href=\"/wiki/a\">b</a></i></p>\n",
+    "{{citation|citation=Aimer <!-- x -->}}": "<p><span class=\"citation\">Aimer </span></p>\n",
+    "a</blockquote>b":                        "<p>ab</p>\n",
+    "<small>a<b>b</small>c":                  "<p><small>a<b>b</b></small>c</p>\n",
+    "''<small>a'' b":                         "<p><i><small>a</small></i> b</p>\n",
+    "{{citation|<small>a}}</small>":          "<p><span class=\"citation\"><small>a</small></span></p>\n",
+  }
+  for doc, expected := range cases {
+    assertEqual(t, "HTML for "+doc, expected, RenderHTML(Parse(Tokenize(doc))))
+  }
+}
+
+func TestHTMLBlocks(t *testing.T) {
+  doc := "== Titre ==\n* un\n** deux\n* trois\n\nTexte\nsuite\n"
+  expected := "<h2>Titre</h2>\n<ul>\n<li>un<ul>\n<li>deux</li></ul>\n</li>\n<li>trois</li></ul>\n<p>Texte\nsuite</p>\n"
+  assertEqual(t, "HTML blocks", expected, RenderHTML(Parse(Tokenize(doc))))
+}
+
+func TestHTMLPoem(t *testing.T) {
+  doc := "{{citation|citation=<poem>\nLe ciel est, par-dessus le toit,\n''Si bleu'', si calme !\n</poem>}}"
+  expected := "<div class=\"citation\"><div class=\"poem\">Le ciel est, par-dessus le toit,<br />\n<i>Si bleu</i>, si calme !</div></div>"
+  assertEqual(t, "HTML poem", expected, RenderHTML(Parse(Tokenize(doc))))
+}
+
+func TestHTMLLinkURL(t *testing.T) {
+  h := NewHTMLRenderer()
+  h.LinkURL = func(target string) string {
+    return "https://fr.wikiquote.org/wiki/" + target
+  }
+  assertEqual(t, "Custom URL", "<p><a href=\"https://fr.wikiquote.org/wiki/Amour\">Amour</a></p>\n", h.Render(Parse(Tokenize("[[Amour]]"))))
+}
@@ -0,0 +1,225 @@
+// Package importer is the command line shared by the quote importers: it
+// extracts the quotes of a dump into a CSV file with a language profile,
+// running the pipeline with checkpoints and a quarantine. domenech and
+// hodgson only differ by the defaults they give it.
+package importer
+
+import (
+  "context"
+  "flag"
+  "fmt"
+  "github.com/golang/glog"
+  . "github.com/octplane/wikiquote-parser"
+  "github.com/octplane/wikiquote-parser/dump"
+  "github.com/octplane/wikiquote-parser/pipeline"
+  "github.com/octplane/wikiquote-parser/quotes"
+  "os"
+  "time"
+)
+
+// Defaults are what differs from an importer to another
+//    - Dump is the default of the -dump flag
+//    - Language is the built in profile used without -profile, a key of quotes.Profiles
+type Defaults struct {
+  Dump     string
+  Language string
+}
+
+const layout = "20060102_1504"
+
+// glogLogger sends the library logs to glog, debug and info messages at the given verbosity
+type glogLogger glog.Level
+
+func logLine(msg string, args []interface{}) string {
+  for ix := 0; ix+1 < len(args); ix += 2 {
+    msg += fmt.Sprintf(" %v=%v", args[ix], args[ix+1])
+  }
+  return msg
+}
+
+func (l glogLogger) Debug(msg string, args ...interface{}) {
+  if glog.V(glog.Level(l)) {
+    glog.InfoDepth(1, logLine(msg, args))
+  }
+}
+
+func (l glogLogger) Info(msg string, args ...interface{}) {
+  if glog.V(glog.Level(l)) {
+    glog.InfoDepth(1, logLine(msg, args))
+  }
+}
+
+func (l glogLogger) Warn(msg string, args ...interface{}) {
+  glog.WarningDepth(1, logLine(msg, args))
+}
+
+func (l glogLogger) Error(msg string, args ...interface{}) {
+  glog.ErrorDepth(1, logLine(msg, args))
+}
+
+// importer holds what the workers share
+//    - parseOptions bound the parse of every page, the pages exceeding them go to quarantine
+//    - profile holds the templates and the layout of the wikiquote quotes
+type importer struct {
+  namespaces     *dump.NamespaceFilter
+  parseOptions   ParseOptions
+  quarantine     *pipeline.Quarantine
+  profile        *quotes.Profile
+  extractOptions quotes.ExtractOptions
+}
+
+// Main parses the command line and runs the import, it exits on the errors
+func Main(d Defaults) {
+  im := &importer{}
+
+  dumpFile := flag.String("dump", d.Dump, "MediaWiki XML dump, .xml or .xml.bz2")
+  include := flag.String("ns", "", "comma separated namespaces to extract, by number or name, all by default")
+  exclude := flag.String("exclude-ns", "Template,Category,MediaWiki,Help", "comma separated namespaces to skip")
+  workers := flag.Int("workers", 0, "parsing goroutines, and decompressing ones for a multistream dump with its index, one per CPU by default")
+  profileFile := flag.String("profile", "", "JSON language profile, see profiles/, the built in "+d.Language+" profile by default")
+  checkpoint := flag.String("checkpoint", "", "progress of the extraction, saved periodically, <output>.checkpoint by default")
+  every := flag.Int("checkpoint-every", pipeline.DefaultCheckpointEvery, "pages between two checkpoints")
+  resume := flag.Bool("resume", false, "skip the pages done according to -checkpoint and append to its output")
+  quarantineFile := flag.String("quarantine", "", "pages the parser gave up on, appended one JSON object per line, <output>.quarantine by default")
+  flag.BoolVar(&im.extractOptions.SplitDialogues, "split-dialogues", false, "also write every line of a dialogue as a quote of its speaker")
+  flag.IntVar(&im.parseOptions.MaxSteps, "max-steps", 5000000, "tokens the parser may consume on a page, 0 for no bound")
+  flag.DurationVar(&im.parseOptions.Timeout, "timeout", 30*time.Second, "time the parser may spend on a page, 0 for no bound")
+  flag.Parse()
+  SetLogger(glogLogger(2))
+  if glog.V(7) {
+    im.parseOptions.Trace = glogLogger(7)
+  }
+
+  im.profile = quotes.Profiles[d.Language]
+  if *profileFile != "" {
+    fi, err := os.Open(*profileFile)
+    if err != nil {
+      glog.Fatal(err)
+    }
+    im.profile, err = quotes.LoadProfile(fi)
+    fi.Close()
+    if err != nil {
+      glog.Fatalf("%s: %s", *profileFile, err)
+    }
+  }
+  if im.profile == nil {
+    glog.Fatalf("no built in profile for %q, give one with -profile", d.Language)
+  }
+  if *checkpoint == "" {
+    *checkpoint = im.profile.Output + ".checkpoint"
+  }
+  if *quarantineFile == "" {
+    *quarantineFile = im.profile.Output + ".quarantine"
+  }
+
+  // the streams of a multistream dump are decompressed in parallel
+  source, siteInfo, closeDump, err := pipeline.OpenPages(*dumpFile, *workers)
+  if err != nil {
+    panic(err)
+  }
+  defer closeDump()
+
+  im.namespaces, err = dump.NewNamespaceFilter(siteInfo, dump.ParseNamespaceList(*include), dump.ParseNamespaceList(*exclude))
+  if err != nil {
+    glog.Fatal(err)
+  }
+
+  var output *pipeline.CheckpointWriter
+  if *resume {
+    output, err = pipeline.ResumeCheckpointWriter(*checkpoint, *dumpFile)
+    if err != nil {
+      glog.Fatal(err)
+    }
+    c := output.Checkpoint()
+    glog.Infof("Resuming %s after %d pages, the last one being %d", c.Output, c.Pages, c.LastPageID)
+    source = pipeline.Skip(source, c)
+  } else {
+    t := time.Now()
+    prefix := t.Format(layout)
+    fname := fmt.Sprintf("%s-%s.csv", im.profile.Output, prefix)
+
+    // open output file
+    glog.V(1).Infof("Creating file %s", fname)
+    output, err = pipeline.NewCheckpointWriter(*checkpoint, *dumpFile, fname)
+    if err != nil {
+      glog.Fatal(err)
+    }
+  }
+  output.Every = *every
+
+  im.quarantine, err = pipeline.OpenQuarantine(*quarantineFile)
+  if err != nil {
+    glog.Fatal(err)
+  }
+
+  ctx, source, release := pipeline.OnInterrupt(context.Background(), source, func(msg string) {
+    fmt.Fprintln(os.Stderr, msg)
+  })
+  p := pipeline.Pipeline{
+    Source:  source,
+    Worker:  im.extract,
+    Writer:  output,
+    Workers: *workers,
+  }
+  err = p.Run(ctx)
+  release()
+
+  c := output.Checkpoint()
+  glog.Infof("%d pages done, %d quotes in %s", c.Pages, c.Records, c.Output)
+  if im.quarantine.Len() > 0 {
+    glog.Warningf("%d pages quarantined in %s", im.quarantine.Len(), *quarantineFile)
+  }
+  if cerr := output.Close(); err == nil {
+    err = cerr
+  }
+  if cerr := im.quarantine.Close(); err == nil {
+    err = cerr
+  }
+  if err != nil {
+    glog.Fatal(err)
+  }
+}
+
+// extract parses a page and returns its quote records, none when the page
+// namespace is filtered out or when the page is quarantined
+func (im *importer) extract(ctx context.Context, item interface{}) (result interface{}, err error) {
+  page := item.(*dump.Page)
+  title := page.Title
+  glog.V(3).Infof("title is %s", title)
+  none := pipeline.PageRecords{PageID: page.ID}
+
+  if !im.namespaces.Match(page.NS) {
+    glog.V(3).Infof("Ignoring %s", title)
+    return none, nil
+  }
+
+  // a panic of the extraction only loses this page
+  defer func() {
+    if r := recover(); r != nil {
+      glog.Warningf("Quarantining %s: %v", title, r)
+      result, err = none, im.quarantine.Add(page, fmt.Sprintf("panic: %v", r))
+    }
+  }()
+
+  glog.V(1).Infof("Entering %s", title)
+  nodes, diagnostics, err := ParseChecked(page.Revision.Text, im.parseOptions)
+  for _, d := range diagnostics {
+    d.Page = title
+    glog.V(2).Infoln(d.String())
+  }
+  if err != nil {
+    glog.Warningf("Quarantining %s: %s", title, err)
+    return none, im.quarantine.Add(page, err.Error())
+  }
+
+  found, rejections := im.profile.ExtractWithOptions(quotes.Page{ID: page.ID, Title: title}, nodes, im.extractOptions)
+  for _, r := range rejections {
+    glog.V(2).Infoln(r.String())
+  }
+  records := make([][]string, 0, len(found))
+  for _, q := range found {
+    glog.V(2).Infof("Quote: %s", q.Quote)
+    records = append(records, q.Record())
+  }
+  return pipeline.PageRecords{PageID: page.ID, Records: records}, nil
+}
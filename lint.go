@@ -0,0 +1,186 @@
+package wikimediaparser
+
+import (
+  "fmt"
+  "sort"
+  "strings"
+)
+
+// Lint parses the page source and reports, besides the parser diagnostics:
+//    - unclosed-template and unclosed-link for {{ and [[ never closed, or not closed on their line for [[
+//    - empty-template for templates without a name or with blank parameters only
+//    - duplicate-param for named parameters given twice
+//    - suspicious-bracket for [ that does not open an external link, except [...], or that is not closed on its line
+//    - unbalanced-heading for headings with a different count of = on each side
+//
+// The diagnostics are located in source and sorted by position.
+func Lint(source string) Diagnostics {
+  return LintWithOptions(source, ParseOptions{})
+}
+
+// LintWithOptions is Lint with a parse budget. A page exceeding it is
+// reported with a budget-exceeded error, a page breaking the parser with a
+// parse-failed one, and both only get the checks working on the tokens.
+func LintWithOptions(source string, opts ParseOptions) Diagnostics {
+  items := Tokenize(source)
+  nodes, ret, err := parseChecked(items, opts)
+  if err != nil {
+    code := "parse-failed"
+    if _, ok := err.(*BudgetError); ok {
+      code = "budget-exceeded"
+    }
+    ret = append(ret, Diagnostic{Code: code, Message: err.Error(), Severity: SeverityError, Span: Span{End: len(source)}})
+  }
+
+  ret = append(ret, lintBrackets(items)...)
+  ret = append(ret, lintHeadings(source)...)
+  Inspect(nodes, func(n *Node, parent *Node, path Path) bool {
+    if n == nil {
+      return true
+    }
+    switch n.Typ {
+    case NodeTemplate:
+      if d, ok := lintEmptyTemplate(n); ok {
+        ret = append(ret, d)
+      }
+    case NodeELink:
+      if d, ok := lintELink(n); ok {
+        ret = append(ret, d)
+      }
+    }
+    return true
+  })
+
+  sort.SliceStable(ret, func(i, j int) bool {
+    return ret[i].Span.Start < ret[j].Span.Start
+  })
+  ret.Locate(source)
+  return ret
+}
+
+var unclosed = map[token]struct {
+  code   string
+  closer token
+}{
+  templateStart:    {"unclosed-template", templateEnd},
+  placeholderStart: {"unclosed-template", placeholderEnd},
+  linkStart:        {"unclosed-link", linkEnd},
+}
+
+// lintBrackets matches the {{ and [[ with their closing tokens, ignoring
+// nowiki sections. The [[ and [ not closed on their line, which the parser
+// reads as text, are reported right away.
+func lintBrackets(items []item) Diagnostics {
+  ret := make(Diagnostics, 0)
+  stack := make([]item, 0)
+  report := func(it item) {
+    ret = append(ret, Diagnostic{
+      Code:     unclosed[it.Typ].code,
+      Message:  "missing " + unclosed[it.Typ].closer.String(),
+      Severity: SeverityError,
+      Span:     Span{Start: it.Pos, End: it.Pos + len(it.Val)},
+    })
+  }
+
+  nowiki := false
+  for ix, it := range items {
+    switch {
+    case it.Typ == tokenNowikiStart:
+      nowiki = true
+    case it.Typ == tokenNowikiEnd:
+      nowiki = false
+    case nowiki:
+    case it.Typ == linkStart && !closedOnLine(items, ix):
+      report(it)
+      ret[len(ret)-1].Message += " on its line"
+    case it.Typ == tokenELnkStart && !closedOnLine(items, ix):
+      ret = append(ret, Diagnostic{
+        Code:     "suspicious-bracket",
+        Message:  "[ is not closed on its line",
+        Severity: SeverityWarning,
+        Span:     Span{Start: it.Pos, End: it.Pos + len(it.Val)},
+      })
+    case it.Typ == templateStart || it.Typ == placeholderStart || it.Typ == linkStart:
+      stack = append(stack, it)
+    case it.Typ == templateEnd || it.Typ == placeholderEnd || it.Typ == linkEnd:
+      // an opener left inside the closed construct is unclosed
+      for ix := len(stack) - 1; ix >= 0; ix-- {
+        if unclosed[stack[ix].Typ].closer == it.Typ {
+          for _, open := range stack[ix+1:] {
+            report(open)
+          }
+          stack = stack[:ix]
+          break
+        }
+      }
+    }
+  }
+  for _, open := range stack {
+    report(open)
+  }
+  return ret
+}
+
+// lintHeadings compares the = on both sides of the heading lines
+func lintHeadings(source string) Diagnostics {
+  ret := make(Diagnostics, 0)
+  offset := 0
+  for _, line := range strings.SplitAfter(source, "\n") {
+    start := offset
+    offset += len(line)
+    line = strings.TrimRight(line, " \t\r\n")
+    if !strings.HasPrefix(line, "=") {
+      continue
+    }
+    left := len(line) - len(strings.TrimLeft(line, "="))
+    right := len(line) - len(strings.TrimRight(line, "="))
+    if left == len(line) || left == right {
+      continue
+    }
+    ret = append(ret, Diagnostic{
+      Code:     "unbalanced-heading",
+      Message:  "heading opened with " + strings.Repeat("=", left) + " and closed with " + strings.Repeat("=", right),
+      Severity: SeverityWarning,
+      Span:     Span{Start: start, End: start + len(line)},
+    })
+  }
+  return ret
+}
+
+func lintEmptyTemplate(n *Node) (Diagnostic, bool) {
+  d := Diagnostic{Code: "empty-template", Severity: SeverityWarning, Span: n.Span}
+  name := strings.TrimSpace(n.StringParamOrEmpty("name"))
+  if name == "" {
+    d.Message = "template without a name"
+    return d, true
+  }
+  if len(n.Params) == 0 && len(n.NamedParams) == 1 {
+    return d, false
+  }
+  for _, k := range n.namedKeys() {
+    if k != "name" && strings.TrimSpace(n.NamedParams[k].StringRepresentation()) != "" {
+      return d, false
+    }
+  }
+  for _, p := range n.Params {
+    if strings.TrimSpace(p.StringRepresentation()) != "" {
+      return d, false
+    }
+  }
+  d.Message = "template " + name + " has blank parameters only"
+  return d, true
+}
+
+func lintELink(n *Node) (Diagnostic, bool) {
+  target := strings.TrimSpace(n.StringParamOrEmpty("link"))
+  // [...] marks a cut in a quote
+  if safeURL(target) || target == "..." || target == "…" {
+    return Diagnostic{}, false
+  }
+  return Diagnostic{
+    Code:     "suspicious-bracket",
+    Message:  fmt.Sprintf("[ does not open an external link, %q is not an URL", target),
+    Severity: SeverityWarning,
+    Span:     n.Span,
+  }, true
+}
@@ -0,0 +1,71 @@
+package wikimediaparser
+
+import (
+  "fmt"
+  "strings"
+  "testing"
+)
+
+func lintCodes(source string) []string {
+  return codesOf(Lint(source))
+}
+
+func codesOf(ds Diagnostics) []string {
+  ret := make([]string, 0)
+  for _, d := range ds {
+    ret = append(ret, fmt.Sprintf("%d:%d %s", d.Line, d.Column, d.Code))
+  }
+  return ret
+}
+
+func TestLintClean(t *testing.T) {
+  assertEqual(t, "clean page", "[]", fmt.Sprint(lintCodes("== Citations ==\n{{citation|Vu [[Victor Hugo]] [http://fr.wikiquote.org ici] [...]}}\n")))
+}
+
+func TestLintUnclosed(t *testing.T) {
+  codes := lintCodes("a {{w|b\n\n[[c]] [[d|e\n\nf")
+  // the parser reads the [[ left open on its line as text: the lint reports
+  // it, the parser no longer runs into the end of the page
+  assertEqual(t, "unclosed", "[1:3 unclosed-template 1:6 unexpected-eof 3:7 unclosed-link]", fmt.Sprint(codes))
+
+  codes = lintCodes("{{citation|[[lien}} suite")
+  assertEqual(t, "link closed by the template", "[1:12 unclosed-link]", fmt.Sprint(codes))
+}
+
+func TestLintLeftOpenOnTheirLine(t *testing.T) {
+  assertEqual(t, "open bracket", "[1:7 suspicious-bracket]", fmt.Sprint(lintCodes("Suite [sic\nFin")))
+  assertEqual(t, "open external link", "[1:3 suspicious-bracket]", fmt.Sprint(lintCodes("x [http://a b\ny")))
+
+  ds := Lint("[[a\nb]] [[c]]")
+  assertEqual(t, "link closed on the next line", "[1:1 unclosed-link]", fmt.Sprint(lintCodes("[[a\nb]] [[c]]")))
+  assertEqual(t, "unclosed message", "missing ]] on its line", ds[0].Message)
+}
+
+func TestLintTemplates(t *testing.T) {
+  assertEqual(t, "empty name", "[1:1 empty-template]", fmt.Sprint(lintCodes("{{ }}")))
+  assertEqual(t, "blank parameters", "[1:1 empty-template]", fmt.Sprint(lintCodes("{{citation| |auteur=}}")))
+  assertEqual(t, "no parameter", "[]", fmt.Sprint(lintCodes("{{ébauche}}")))
+  assertEqual(t, "duplicate", "[1:21 duplicate-param]", fmt.Sprint(lintCodes("{{Réf Livre|titre=A|titre=B}}")))
+}
+
+func TestLintBracketsAndHeadings(t *testing.T) {
+  ds := Lint("Dans [Stendhal] ou [http://x.org lien]")
+  assertEqual(t, "suspicious count", 1, len(ds))
+  assertEqual(t, "suspicious message", "[ does not open an external link, \"Stendhal\" is not an URL", ds[0].Message)
+  assertEqual(t, "suspicious severity", SeverityWarning, ds[0].Severity)
+
+  assertEqual(t, "unbalanced heading", "[2:1 unbalanced-heading]", fmt.Sprint(lintCodes("x\n== Titre ===\ny")))
+  assertEqual(t, "balanced heading", "[]", fmt.Sprint(lintCodes("x\n=== Titre ===\ny")))
+}
+
+func TestLintBudget(t *testing.T) {
+  source := "a {{w|b\n" + strings.Repeat("{{citation|citation=[[Amour]]}}\n", 20)
+  codes := lintCodes(source)
+  if len(codes) != 2 {
+    t.Fatalf("Unexpected diagnostics %v", codes)
+  }
+
+  ds := LintWithOptions(source, ParseOptions{MaxSteps: 10})
+  assertEqual(t, "budget", "[1:1 budget-exceeded 1:3 unclosed-template]", fmt.Sprint(codesOf(ds)))
+  assertEqual(t, "severity", SeverityError, ds[0].Severity)
+}
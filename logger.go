@@ -0,0 +1,42 @@
+package wikimediaparser
+
+import (
+  "time"
+)
+
+// Logger receives the log messages of the package. Its methods take a message
+// and alternating keys and values, so that a *slog.Logger can be used as is.
+type Logger interface {
+  Debug(msg string, args ...interface{})
+  Info(msg string, args ...interface{})
+  Warn(msg string, args ...interface{})
+  Error(msg string, args ...interface{})
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(msg string, args ...interface{}) {}
+func (nopLogger) Info(msg string, args ...interface{})  {}
+func (nopLogger) Warn(msg string, args ...interface{})  {}
+func (nopLogger) Error(msg string, args ...interface{}) {}
+
+var logger Logger = nopLogger{}
+
+// SetLogger sets the logger of the package, nil restores the default that drops everything.
+// The parse events are not sent there, see ParseOptions.Trace.
+func SetLogger(l Logger) {
+  if l == nil {
+    l = nopLogger{}
+  }
+  logger = l
+}
+
+// ParseOptions tune a single ParseWithOptions call
+//    - Trace receives the parse events at debug level, they are not formatted when Trace is nil
+//    - MaxSteps bounds the tokens consumed, sub parsers and recoveries included, 0 for no bound
+//    - Timeout bounds the duration of the parse, 0 for no bound
+type ParseOptions struct {
+  Trace    Logger
+  MaxSteps int
+  Timeout  time.Duration
+}
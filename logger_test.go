@@ -0,0 +1,42 @@
+package wikimediaparser
+
+import (
+  "fmt"
+  "testing"
+)
+
+type recordingLogger struct {
+  messages []string
+}
+
+func (l *recordingLogger) record(level string, msg string, args []interface{}) {
+  l.messages = append(l.messages, fmt.Sprintf("%s %s %v", level, msg, args))
+}
+
+func (l *recordingLogger) Debug(msg string, args ...interface{}) { l.record("DEBUG", msg, args) }
+func (l *recordingLogger) Info(msg string, args ...interface{})  { l.record("INFO", msg, args) }
+func (l *recordingLogger) Warn(msg string, args ...interface{})  { l.record("WARN", msg, args) }
+func (l *recordingLogger) Error(msg string, args ...interface{}) { l.record("ERROR", msg, args) }
+
+func TestParseTrace(t *testing.T) {
+  trace := &recordingLogger{}
+  traced, _ := ParseWithOptions("{{w|Victor Hugo}}", ParseOptions{Trace: trace})
+  if len(trace.messages) == 0 {
+    t.Fatal("Expected parse events in the trace logger")
+  }
+  assertEqual(t, "event level and parser", "DEBUG", trace.messages[0][:5])
+
+  untraced, _ := ParseWithDiagnostics("{{w|Victor Hugo}}")
+  assertEqual(t, "same tree", true, Equal(traced, untraced, EqualOptions{}))
+}
+
+func TestSetLogger(t *testing.T) {
+  l := &recordingLogger{}
+  SetLogger(l)
+  defer SetLogger(nil)
+
+  n := Node{Typ: NodeTemplate, NamedParams: map[string]Nodes{}}
+  n.StringParam("name")
+  assertEqual(t, "messages", 1, len(l.messages))
+  assertEqual(t, "message", "DEBUG unable to extract parameter [param name node Template: ]", l.messages[0])
+}
@@ -0,0 +1,435 @@
+package wikimediaparser
+
+import (
+  "bytes"
+  "fmt"
+  "html"
+  "regexp"
+  "strconv"
+  "strings"
+)
+
+// MarkdownTemplateRenderer renders a template node through the MarkdownWriter
+type MarkdownTemplateRenderer func(w *MarkdownWriter, n *Node)
+
+// MarkdownRenderer turns Nodes into CommonMark, the Markdown counterpart of StringRepresentation.
+//    - LinkURL maps an internal link target to its URL, an empty URL renders the label only
+//    - Templates maps canonical template names to their renderer
+//    - Fallback renders the templates missing from the registry (nil renders nothing)
+//
+// Titles become # headings, '' and ''' become * and **, * and # lists are kept
+// and the Markdown metacharacters of the text are escaped.
+type MarkdownRenderer struct {
+  LinkURL   func(target string) string
+  Templates map[string]MarkdownTemplateRenderer
+  Fallback  MarkdownTemplateRenderer
+}
+
+// NewMarkdownRenderer returns a renderer linking to /wiki/ and knowing the common wikiquote templates
+func NewMarkdownRenderer() *MarkdownRenderer {
+  m := &MarkdownRenderer{
+    LinkURL:   defaultLinkURL,
+    Templates: make(map[string]MarkdownTemplateRenderer),
+    Fallback: func(w *MarkdownWriter, n *Node) {
+      w.Param(n, 0)
+    },
+  }
+  m.Register("w", markdownWikipediaLink)
+  m.Register("lang", func(w *MarkdownWriter, n *Node) {
+    if !w.Named(n, "texte", "text") {
+      w.Param(n, 1)
+    }
+  })
+  m.Register("date", func(w *MarkdownWriter, n *Node) {
+    w.Text(defaultTextRenderer.RenderNode(n))
+  })
+  m.Register("citation", func(w *MarkdownWriter, n *Node) {
+    if !w.Named(n, "citation") {
+      w.Param(n, 0)
+    }
+  })
+  m.Register("tab", func(w *MarkdownWriter, n *Node) {
+    w.Raw(" ")
+  })
+  m.Register("!", func(w *MarkdownWriter, n *Node) {
+    w.Text("|")
+  })
+  return m
+}
+
+var defaultMarkdownRenderer = NewMarkdownRenderer()
+
+// RenderMarkdown renders the nodes with the default MarkdownRenderer
+func RenderMarkdown(ns Nodes) string {
+  return defaultMarkdownRenderer.Render(ns)
+}
+
+// Register sets the renderer for a template name
+func (m *MarkdownRenderer) Register(name string, fn MarkdownTemplateRenderer) {
+  m.Templates[CanonicalTemplateName(name)] = fn
+}
+
+type markdownBlock int
+
+const (
+  noBlock = markdownBlock(iota)
+  paragraphBlock
+  listBlock
+  headingBlock
+)
+
+// Render renders a whole page, blocks being separated by blank lines
+func (m *MarkdownRenderer) Render(ns Nodes) string {
+  w := &MarkdownWriter{m: m}
+  for ix, line := range splitLines(ns) {
+    if w.poem {
+      if ix > 0 {
+        w.newline()
+      }
+      w.Inline(line)
+      continue
+    }
+    w.closeEmphasis()
+
+    if len(line) == 1 && line[0].Typ == NodeTitle {
+      level, _ := strconv.Atoi(line[0].StringParamOrEmpty("level"))
+      if level < 1 {
+        level = 1
+      } else if level > 6 {
+        level = 6
+      }
+      w.block(headingBlock)
+      w.write(strings.Repeat("#", level) + " ")
+      w.Inline(trimNodes(line[0].NamedParams["title"]))
+      w.closeEmphasis()
+      continue
+    }
+
+    line = mergeText(line)
+    lead := ""
+    if len(line) > 0 && line[0].Typ == NodeText {
+      lead = line[0].Val
+    }
+
+    switch prefix := listPrefix(lead); {
+    case len(line) == 0 || len(line) == 1 && line[0].Typ == NodeText && strings.TrimSpace(lead) == "":
+      w.block(noBlock)
+    case prefix != "" && strings.Trim(prefix, ":;") != "":
+      w.block(listBlock)
+      w.write(listIndent(prefix))
+      line[0].Val = strings.TrimLeft(lead[len(prefix):], " ")
+      w.atLineStart = true
+      w.Inline(line)
+    case prefix != "":
+      // definitions and indentations have no Markdown equivalent
+      w.block(paragraphBlock)
+      line[0].Val = strings.TrimLeft(lead[len(prefix):], " ")
+      w.atLineStart = true
+      if strings.HasSuffix(prefix, ";") {
+        w.Wrap("**", "**", func() { w.Inline(line) })
+      } else {
+        w.Inline(line)
+      }
+    default:
+      w.block(paragraphBlock)
+      w.atLineStart = true
+      w.Inline(line)
+    }
+  }
+  w.closeEmphasis()
+  if w.started {
+    w.write("\n")
+  }
+  return w.buf.String()
+}
+
+// listIndent returns the list marker for a wikitext list prefix, nested lists
+// being indented to the content of their parent item
+func listIndent(prefix string) string {
+  indent := ""
+  for ix := 0; ix < len(prefix)-1; ix++ {
+    if prefix[ix] == '#' {
+      indent += "   "
+    } else {
+      indent += "  "
+    }
+  }
+  if prefix[len(prefix)-1] == '#' {
+    return indent + "1. "
+  }
+  return indent + "- "
+}
+
+// MarkdownWriter accumulates the output of a MarkdownRenderer and tracks the open elements
+type MarkdownWriter struct {
+  m           *MarkdownRenderer
+  buf         bytes.Buffer
+  emphasis    []string
+  current     markdownBlock
+  started     bool
+  atLineStart bool
+  poem        bool
+  poemContent bool
+  breaks      int
+}
+
+func (w *MarkdownWriter) write(s string) {
+  w.buf.WriteString(s)
+}
+
+// block starts a new line of the given kind, with a blank line between different blocks
+func (w *MarkdownWriter) block(kind markdownBlock) {
+  if kind == noBlock {
+    w.current = noBlock
+    return
+  }
+  if w.started {
+    w.write("\n")
+    if kind != w.current || kind == headingBlock {
+      w.write("\n")
+    }
+  }
+  w.current = kind
+  w.started = true
+}
+
+// Raw writes trusted Markdown
+func (w *MarkdownWriter) Raw(s string) {
+  w.flushBreaks()
+  w.write(s)
+  w.atLineStart = false
+}
+
+// Text writes escaped text
+func (w *MarkdownWriter) Text(s string) {
+  if s == "" {
+    return
+  }
+  w.flushBreaks()
+  w.write(escapeMarkdown(s, w.atLineStart))
+  w.atLineStart = false
+}
+
+var markdownSpecials = strings.NewReplacer(
+  `\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`,
+  "<", `\<`, ">", `\>`, "#", `\#`, "|", `\|`, "~", `\~`,
+)
+
+var markdownLineStart = regexp.MustCompile(`^(\s*)([-+=]|\d+[.)])(\s|$)`)
+
+func escapeMarkdown(s string, lineStart bool) string {
+  s = markdownSpecials.Replace(s)
+  if lineStart {
+    // avoid turning text into list items or setext headings
+    if loc := markdownLineStart.FindStringSubmatchIndex(s); loc != nil {
+      s = s[:loc[5]-1] + `\` + s[loc[5]-1:]
+    }
+  }
+  return s
+}
+
+// Inline renders nodes without block structure
+func (w *MarkdownWriter) Inline(ns Nodes) {
+  for _, n := range mergeText(ns) {
+    w.node(&n)
+  }
+}
+
+// Param renders the positional parameter ix of a node
+func (w *MarkdownWriter) Param(n *Node, ix int) {
+  if ix >= 0 && ix < len(n.Params) {
+    w.Inline(trimNodes(n.Params[ix]))
+  }
+}
+
+// Named renders the first existing named parameter among keys
+func (w *MarkdownWriter) Named(n *Node, keys ...string) bool {
+  for _, k := range keys {
+    if v, ok := n.NamedParams[k]; ok {
+      w.Inline(trimNodes(v))
+      return true
+    }
+  }
+  return false
+}
+
+// Wrap writes the trusted open and close markers around fn output, closing the emphasis opened inside
+func (w *MarkdownWriter) Wrap(open string, close string, fn func()) {
+  w.Raw(open)
+  depth := len(w.emphasis)
+  fn()
+  for len(w.emphasis) > depth {
+    w.toggle(w.emphasis[len(w.emphasis)-1])
+  }
+  w.write(close)
+}
+
+func (w *MarkdownWriter) node(n *Node) {
+  switch n.Typ {
+  case NodeText:
+    w.text(n.Val)
+  case NodeLink:
+    target := strings.TrimSpace(n.StringParamOrEmpty("link"))
+    label := n.NamedParams["link"]
+    if len(n.Params) > 0 {
+      label = n.Params[len(n.Params)-1]
+    }
+    w.link(w.m.LinkURL(target), label)
+  case NodeELink:
+    target := strings.TrimSpace(n.StringParamOrEmpty("link"))
+    label := n.NamedParams["link"]
+    if len(n.Params) > 0 {
+      label = n.Params[0]
+    }
+    if !safeURL(target) {
+      target = ""
+    }
+    w.link(target, label)
+  case NodeTitle:
+    w.Inline(n.NamedParams["title"])
+  case NodeTemplate:
+    fn, ok := w.m.Templates[CanonicalTemplateName(n.StringParam("name"))]
+    if !ok {
+      fn = w.m.Fallback
+    }
+    if fn != nil {
+      fn(w, n)
+    }
+  }
+}
+
+var markdownURL = strings.NewReplacer(" ", "%20", "(", "%28", ")", "%29", "<", "%3C", ">", "%3E")
+
+func (w *MarkdownWriter) link(href string, label Nodes) {
+  label = trimNodes(label)
+  if href == "" || len(label) == 0 {
+    w.Inline(label)
+    return
+  }
+  w.Wrap("[", fmt.Sprintf("](%s)", markdownURL.Replace(href)), func() {
+    w.Inline(label)
+  })
+}
+
+// text scans raw wikitext for emphasis, HTML tags and line feeds
+func (w *MarkdownWriter) text(s string) {
+  last := 0
+  for _, loc := range inlineMarkup.FindAllStringIndex(s, -1) {
+    w.Text(html.UnescapeString(s[last:loc[0]]))
+    if w.poem && strings.TrimSpace(s[last:loc[0]]) != "" {
+      w.poemContent = true
+    }
+    last = loc[1]
+    m := s[loc[0]:loc[1]]
+
+    switch {
+    case m == "\n":
+      w.newline()
+    case m[0] == '\'':
+      w.apostrophes(len(m))
+    case strings.HasPrefix(m, "<!--"):
+    case brTag.MatchString(m):
+      w.Raw("\\\n")
+    default:
+      w.tag(m)
+    }
+  }
+  w.Text(html.UnescapeString(s[last:]))
+  if w.poem && strings.TrimSpace(s[last:]) != "" {
+    w.poemContent = true
+  }
+}
+
+func (w *MarkdownWriter) tag(m string) {
+  parts := tagMarkup.FindStringSubmatch(m)
+  closing := parts[1] == "/"
+  switch name := strings.ToLower(parts[2]); {
+  case name == "poem" && !closing:
+    w.closeEmphasis()
+    w.poem = true
+    w.poemContent = false
+  case name == "poem" && closing:
+    w.closeEmphasis()
+    w.breaks = 0
+    w.poem = false
+  case name == "i" || name == "em":
+    w.toggle("*")
+  case name == "b" || name == "strong":
+    w.toggle("**")
+  default:
+    w.Text(m)
+  }
+}
+
+// apostrophes handles '' (italic), ''' (bold) and ''''' (both), extra apostrophes are text
+func (w *MarkdownWriter) apostrophes(count int) {
+  switch {
+  case count == 2:
+    w.toggle("*")
+  case count == 3:
+    w.toggle("**")
+  case count == 4:
+    w.Text("'")
+    w.toggle("**")
+  default:
+    w.Text(strings.Repeat("'", count-5))
+    w.toggle("**")
+    w.toggle("*")
+  }
+}
+
+// toggle opens or closes an emphasis marker, reopening the markers opened after it
+func (w *MarkdownWriter) toggle(marker string) {
+  for ix := len(w.emphasis) - 1; ix >= 0; ix-- {
+    if w.emphasis[ix] != marker {
+      continue
+    }
+    above := append([]string{}, w.emphasis[ix+1:]...)
+    for j := len(w.emphasis) - 1; j >= ix; j-- {
+      w.write(w.emphasis[j])
+    }
+    w.emphasis = append(w.emphasis[:ix], above...)
+    for _, t := range above {
+      w.write(t)
+    }
+    return
+  }
+  w.Raw(marker)
+  w.emphasis = append(w.emphasis, marker)
+}
+
+func (w *MarkdownWriter) closeEmphasis() {
+  for len(w.emphasis) > 0 {
+    w.toggle(w.emphasis[len(w.emphasis)-1])
+  }
+}
+
+// newline ends a line: emphasis is closed and poems keep their line breaks
+func (w *MarkdownWriter) newline() {
+  w.closeEmphasis()
+  if !w.poem {
+    w.write("\n")
+    w.atLineStart = true
+  } else if w.poemContent {
+    w.breaks += 1
+  }
+}
+
+func (w *MarkdownWriter) flushBreaks() {
+  for ; w.breaks > 0; w.breaks-- {
+    w.write("\\\n")
+    w.atLineStart = true
+  }
+}
+
+// {{w|Victor Hugo}} or {{w|Victor Hugo|Hugo}}
+func markdownWikipediaLink(w *MarkdownWriter, n *Node) {
+  if len(n.Params) == 0 {
+    return
+  }
+  label := n.Params[0]
+  if len(n.Params) > 1 {
+    label = n.Params[1]
+  }
+  w.link(w.m.LinkURL("w:"+strings.TrimSpace(n.Params[0].StringRepresentation())), label)
+}
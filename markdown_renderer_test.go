@@ -0,0 +1,33 @@
+package wikimediaparser
+
+import (
+  "testing"
+)
+
+func TestMarkdownInline(t *testing.T) {
+  cases := map[string]string{
+    "Il ''faut'' '''aimer''' la vie":       "Il *faut* **aimer** la vie\n",
+    "[[Victor Hugo (écrivain)|Hugo]]":      "[Hugo](/wiki/Victor_Hugo_%28%C3%A9crivain%29)\n",
+    "[http://url.com/ un site]":            "[un site](http://url.com/)\n",
+    "[javascript:alert(1) clic]":           "clic\n",
+    "2*3 _x_ <b>gras</b> #1":               "2\\*3 \\_x\\_ **gras** \\#1\n",
+    "- pas une liste":                      "\\- pas une liste\n",
+    "{{w|Victor Hugo}}, {{inconnu|texte}}": "[Victor Hugo](/wiki/w:Victor_Hugo), texte\n",
+    "''ouvert":                             "*ouvert*\n",
+  }
+  for doc, expected := range cases {
+    assertEqual(t, "Markdown for "+doc, expected, RenderMarkdown(Parse(Tokenize(doc))))
+  }
+}
+
+func TestMarkdownBlocks(t *testing.T) {
+  doc := "== Titre ==\n* un\n** deux\n# trois\nTexte\nsuite\n\nAutre paragraphe\n"
+  expected := "## Titre\n\n- un\n  - deux\n1. trois\n\nTexte\nsuite\n\nAutre paragraphe\n"
+  assertEqual(t, "Markdown blocks", expected, RenderMarkdown(Parse(Tokenize(doc))))
+}
+
+func TestMarkdownPoem(t *testing.T) {
+  doc := "{{citation|citation=<poem>\nLe ciel est, par-dessus le toit,\n''Si bleu'', si calme !\n</poem>}}"
+  expected := "Le ciel est, par-dessus le toit,\\\n*Si bleu*, si calme !\n"
+  assertEqual(t, "Markdown poem", expected, RenderMarkdown(Parse(Tokenize(doc))))
+}
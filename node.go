@@ -2,7 +2,6 @@ package wikimediaparser
 
 import (
   "fmt"
-  "github.com/golang/glog"
   "strings"
 )
 
@@ -11,16 +10,17 @@ import (
 //    - a string val Val
 //    - a list of named parameters which are actually Node Lists
 //    -a list of anonymous parameters, a Node list again
+//    - the Span of source it was parsed from
 type Node struct {
   Typ         nodeType
   Val         string
   NamedParams map[string]Nodes
   Params      []Nodes
+  Span        Span
 }
 
 // Return the Node text content, without any decoration
 func (n *Node) StringRepresentation() string {
-  glog.V(7).Infof("stringRepresentation for %+v", n)
   switch n.Typ {
   case NodeText, NodeInvalid:
     return n.Val
@@ -61,7 +61,7 @@ func (n *Node) String() string {
 func (n *Node) StringParam(k string) string {
   param, ok := n.NamedParams[k]
   if !ok {
-    glog.V(2).Infof("Unable to extract parameter \"%s\" for node %s", k, n.String())
+    logger.Debug("unable to extract parameter", "param", k, "node", n)
   } else {
     return param.StringRepresentation()
   }
@@ -69,7 +69,6 @@ func (n *Node) StringParam(k string) string {
 }
 
 func (n *Node) StringParamOrEmpty(k string) string {
-  glog.V(2).Infof("StringParamOrEmpty for %s", k)
   v, ok := n.NamedParams[k]
   if ok {
     ret := v.StringRepresentation()
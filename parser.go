@@ -2,7 +2,7 @@ package wikimediaparser
 
 import (
   "fmt"
-  "github.com/golang/glog"
+  "strings"
 )
 
 type parser struct {
@@ -15,6 +15,10 @@ type parser struct {
   exitTypes    []token
   exitSequence []token
   onError      behaviorOnError
+  diagnostics  *Diagnostics // shared by the whole parser tree
+  mark         int          // diagnostics count when the parser was created
+  trace        Logger
+  budget       *budget // shared by the whole parser tree, nil when unbounded
 }
 
 func create_parser(name string, tokens []item, exitTypes []token, exitSequence []token, onError behaviorOnError) *parser {
@@ -27,6 +31,7 @@ func create_parser(name string, tokens []item, exitTypes []token, exitSequence [
     exitTypes:    exitTypes,
     exitSequence: exitSequence,
     onError:      onError,
+    diagnostics:  &Diagnostics{},
   }
 
   return p
@@ -34,13 +39,19 @@ func create_parser(name string, tokens []item, exitTypes []token, exitSequence [
 
 func (p *parser) currentItem() item {
   if p.pos > len(p.items)-1 {
-    return item{Typ: tokenEOF}
+    eof := item{Typ: tokenEOF}
+    if len(p.items) > 0 {
+      last := p.items[len(p.items)-1]
+      eof.Pos = last.Pos + len(last.Val)
+    }
+    return eof
   }
 
   return p.items[p.pos]
 }
 
 func (p *parser) consume(count int) {
+  p.budget.spend(count)
   p.pos += count
   p.consumed += count
 }
@@ -50,6 +61,7 @@ func (p *parser) backup(count int) {
 }
 
 func (p *parser) eatCurrentItem() item {
+  p.budget.spend(1)
   ret := p.currentItem()
   p.pos += 1
   p.consumed += 1
@@ -58,26 +70,31 @@ func (p *parser) eatCurrentItem() item {
 
 func (p *parser) eat(typ token) {
   it := p.eatCurrentItem()
-  exp := make([]string, 0)
 
   if it.Typ == token(typ) {
     return
   }
-  panic(fmt.Sprintf("Syntax Error at %q\nExpected any of %q, got %q", it.Val, exp, it.Typ.String()))
+  panic(fmt.Sprintf("Syntax Error at %q, expected %q, got %q", it.Val, tokenText(typ), it.Typ.String()))
 }
 
 func (p *parser) eatUntil(typ token) {
   it := p.currentItem()
 
   for it.Typ != token(typ) {
+    if it.Typ == tokenEOF {
+      eofPanic(p, tokenText(typ))
+    }
     it = p.eatCurrentItem()
   }
 }
 
+// log sends a parse event to the trace logger. The parameters are only
+// formatted when tracing, pass Stringers rather than strings.
 func (p *parser) log(format string, params ...interface{}) {
-  parms := []interface{}{p.name}
-  parms = append(parms, params...)
-  glog.V(7).Infof("[%s] "+format, parms...)
+  if p.trace == nil {
+    return
+  }
+  p.trace.Debug(fmt.Sprintf(format, params...), "parser", p.name)
 }
 
 func Cleanup(source string) string {
@@ -92,6 +109,10 @@ func Parse(items []item) (ret Nodes) {
 func (p *parser) CreateParser(name string, tokens []item, exitTypes []token, exitSequence []token, onError behaviorOnError) *parser {
   ret := create_parser(name, tokens, exitTypes, exitSequence, onError)
   ret.parent = p
+  ret.diagnostics = p.diagnostics
+  ret.mark = len(*p.diagnostics)
+  ret.trace = p.trace
+  ret.budget = p.budget
   return ret
 }
 
@@ -103,14 +124,15 @@ func ScanSubArgumentsUntil(name string, parent *parser, node *Node, stop token)
   } else {
     p = create_parser(name, parent.items[parent.pos:], nil, nil, ignoreSectionBehavior)
   }
-  p.log("%s: Creating Argument Parser (%s)\n", name, p.EnvironmentString())
+  p.log("%s: Creating Argument Parser (%s)\n", name, p.environment())
   p.scanSubArgumentsUntil(node, stop)
   return p.consumed
 }
 
 func (p *parser) scanSubArgumentsUntil(node *Node, stop token) {
-  p.log("Sub-scanning until %s", stop.String())
+  p.log("Sub-scanning until %s", stop)
   ret := make([]Node, 0)
+  seen := make(map[string]bool)
 
   defer func() {
     if err := recover(); err != nil {
@@ -122,24 +144,26 @@ func (p *parser) scanSubArgumentsUntil(node *Node, stop token) {
   elt := p.currentItem()
   for elt.Typ != tokenEOF {
     elt = p.currentItem()
-    p.log("Element is now %s, scanning until %s\n", elt.String(), stop.String())
+    p.log("Element is now %s, scanning until %s\n", elt, stop)
     switch elt.Typ {
     case stop, tokenEOF:
       p.consume(1)
-      p.log("Finished sub-scanning at token %s, remains: %s", elt.Typ.String(), p.items[p.pos:])
+      p.log("Finished sub-scanning at token %s, remains: %s", elt.Typ, p.items[p.pos:])
       return
     case itemPipe:
       p.consume(1)
-    case itemText:
-      p.consume(1)
-      if p.currentItem().Typ == tokenEq {
-        k := elt.Val
-        p.consume(1)
+    case itemText, tokenSp:
+      if k, width := p.namedKey(); width > 0 {
+        if seen[k] {
+          last := p.items[p.pos+width-1]
+          p.warn("duplicate-param", Span{Start: elt.Pos, End: last.Pos + len(last.Val)}, "duplicate parameter %q, the last value is kept", k)
+        }
+        seen[k] = true
+        p.consume(width + 1)
         params, consumed := ParseWithEnv(fmt.Sprintf("%s::Param for %s", p.name, k), p, p.items[p.pos:], []token{itemPipe, stop}, nil, abortBehavior)
         node.NamedParams[k] = params
         p.consume(consumed)
       } else {
-        p.backup(1)
         params, consumed := ParseWithEnv(fmt.Sprintf("%s::Anonymous parameter", p.name), p, p.items[p.pos:], []token{itemPipe, stop}, nil, abortBehavior)
         node.Params = append(node.Params, params)
         p.consume(consumed)
@@ -152,6 +176,27 @@ func (p *parser) scanSubArgumentsUntil(node *Node, stop token) {
   }
 }
 
+// namedKey looks for "some key =" at the current position. It returns the
+// trimmed key and the number of items before the =, 0 when there is no key.
+func (p *parser) namedKey() (string, int) {
+  key := ""
+  for ix := p.pos; ix < len(p.items); ix++ {
+    switch it := p.items[ix]; it.Typ {
+    case itemText, tokenSp:
+      key += it.Val
+    case tokenEq:
+      key = strings.TrimSpace(key)
+      if key == "" {
+        return "", 0
+      }
+      return key, ix - p.pos
+    default:
+      return "", 0
+    }
+  }
+  return "", 0
+}
+
 func (p *parser) nextLine() {
   p.log("Will now attempt to find next line for %s\n", p.items[p.pos:])
   for p.pos < len(p.items) {
@@ -188,6 +233,19 @@ func (p *parser) nextBlock() {
   }
 }
 
+type environment struct {
+  p *parser
+}
+
+func (e environment) String() string {
+  return e.p.EnvironmentString()
+}
+
+// environment formats the environment only when printed
+func (p *parser) environment() environment {
+  return environment{p}
+}
+
 func (ev *parser) EnvironmentString() string {
   st := ""
   if len(ev.exitTypes) > 0 {
@@ -215,7 +273,7 @@ func ParseWithEnv(name string, parent *parser, items []item, exitTypes []token,
   } else {
     p = create_parser(name, items, exitTypes, exitSequence, onError)
   }
-  p.log("%s: Creating Parser (%s) with %d items: %+v\n", name, p.EnvironmentString(), len(items), items)
+  p.log("%s: Creating Parser (%s) with %d items: %+v\n", name, p.environment(), len(items), items)
   ret = make([]Node, 0)
 
   ret = p.parse()
@@ -227,6 +285,60 @@ func ParseWithConsumed(items []item) (ret Nodes, consumed int) {
   return ParseWithEnv("top-level", nil, items, nil, nil, ignoreSectionBehavior)
 }
 
+// ParseWithDiagnostics parses the source and reports the errors the parser
+// recovered from, located in the source
+func ParseWithDiagnostics(source string) (Nodes, Diagnostics) {
+  return ParseWithOptions(source, ParseOptions{})
+}
+
+// ParseWithOptions is ParseWithDiagnostics with per call options, like tracing.
+// A parse exceeding its budget returns no node and a budget-exceeded diagnostic.
+func ParseWithOptions(source string, opts ParseOptions) (Nodes, Diagnostics) {
+  ret, diagnostics, err := parseItems(Tokenize(source), opts)
+  if err != nil {
+    diagnostics = append(diagnostics, Diagnostic{Code: "budget-exceeded", Message: err.Error(), Severity: SeverityError, Span: Span{End: len(source)}})
+  }
+  diagnostics.Locate(source)
+  return ret, diagnostics
+}
+
+// ParseChecked is ParseWithOptions for the pages that may break the parser:
+// a parse exceeding its budget returns a *BudgetError, and a panic of the
+// parser is returned as an error too.
+func ParseChecked(source string, opts ParseOptions) (ret Nodes, diagnostics Diagnostics, err error) {
+  ret, diagnostics, err = parseChecked(Tokenize(source), opts)
+  diagnostics.Locate(source)
+  return ret, diagnostics, err
+}
+
+// parseChecked is parseItems returning the panics of the parser as errors
+func parseChecked(items []item, opts ParseOptions) (ret Nodes, diagnostics Diagnostics, err error) {
+  defer func() {
+    if r := recover(); r != nil {
+      ret = nil
+      err = fmt.Errorf("parser panic: %v", r)
+    }
+  }()
+  return parseItems(items, opts)
+}
+
+func parseItems(items []item, opts ParseOptions) (ret Nodes, diagnostics Diagnostics, err error) {
+  p := create_parser("top-level", items, nil, nil, ignoreSectionBehavior)
+  p.trace = opts.Trace
+  p.budget = newBudget(opts)
+  defer func() {
+    if r := recover(); r != nil {
+      b, ok := r.(*BudgetError)
+      if !ok {
+        panic(r)
+      }
+      ret, diagnostics, err = nil, *p.diagnostics, b
+    }
+  }()
+  ret = p.parse()
+  return ret, *p.diagnostics, nil
+}
+
 func (p *parser) parse() (ret Nodes) {
   ret = make([]Node, 0)
 
@@ -236,12 +348,12 @@ func (p *parser) parse() (ret Nodes) {
     }
   }()
 
-  p.log("Starting parsing with exit sequence: %s\n", p.EnvironmentString())
+  p.log("Starting parsing with exit sequence: %s\n", p.environment())
   p.pos = 0
   it := p.currentItem()
 
   for it.Typ != tokenEOF {
-    p.log("token %s\n", it.String())
+    p.log("token %s\n", it)
     // If the exit Sequence match, abort immediately
     if len(p.exitSequence) > 0 {
       matching := 0
@@ -269,6 +381,7 @@ func (p *parser) parse() (ret Nodes) {
     }
 
     var n Node = Node{Typ: NodeInvalid}
+    start := it.Pos
     switch it.Typ {
     case itemText:
       n = Node{Typ: NodeText, Val: it.Val}
@@ -300,7 +413,10 @@ func (p *parser) parse() (ret Nodes) {
     default:
       n = Node{Typ: NodeUnknown, Val: it.Val}
     }
-    p.log("[%s] Appending %s (remains %s), until %s", p.name, n.String(), p.items[p.pos:], p.EnvironmentString())
+    // outer loop will eat the last item of the node
+    last := p.currentItem()
+    n.Span = Span{Start: start, End: last.Pos + len(last.Val)}
+    p.log("[%s] Appending %s (remains %s), until %s", p.name, &n, p.items[p.pos:], p.environment())
     ret = append(ret, n)
 
     if p.currentItem().Typ != tokenEOF {
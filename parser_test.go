@@ -313,3 +313,40 @@ func TestLink2Rendering(t *testing.T) {
   assertEqual(t, "Parse external link title", "\"Minor Threat\" by Aaron Gell in ''GQ'' (March 2006)", p[0].Params[0].StringRepresentation())
 
 }
+
+func TestNamedParameterWithSpaces(t *testing.T) {
+  doc := "{{Réf Livre|année d'origine=1952| page =10|titre=Le Bleu du ciel}}"
+  p := Parse(Tokenize(doc))
+  assertEqual(t, "Key with a space", "1952", p[0].StringParamOrEmpty("année d'origine"))
+  assertEqual(t, "Padded key", "10", p[0].StringParamOrEmpty("page"))
+  assertEqual(t, "No positional parameter", 0, len(p[0].Params))
+}
+
+func TestNamedParameterKeys(t *testing.T) {
+  p := Parse(Tokenize("{{t| = vide|a b c|[[lien]]=x|clé=a=b}}"))
+  assertEqual(t, "Positional parameters", 3, len(p[0].Params))
+  assertEqual(t, "Empty key", " = vide", p[0].Params[0].StringRepresentation())
+  assertEqual(t, "Words without =", "a b c", p[0].Params[1].StringRepresentation())
+  assertEqual(t, "Key with markup", "lien=x", p[0].Params[2].StringRepresentation())
+  assertEqual(t, "First = only", "a=b", p[0].StringParamOrEmpty("clé"))
+  assertEqual(t, "Named parameters", 1, len(p[0].NamedParams)-1)
+}
+
+func TestDuplicateNamedParameter(t *testing.T) {
+  source := "{{Réf Livre|titre=A| titre =B|auteur=C}}"
+  p, diagnostics := ParseWithOptions(source, ParseOptions{})
+  assertEqual(t, "Last value kept", "B", p[0].StringParamOrEmpty("titre"))
+  assertEqual(t, "Diagnostics", 1, len(diagnostics))
+  d := diagnostics[0]
+  assertEqual(t, "Code", "duplicate-param", d.Code)
+  assertEqual(t, "Severity", SeverityWarning, d.Severity)
+  assertEqual(t, "Span of the key", " titre ", source[d.Span.Start:d.Span.End])
+}
+
+func TestLinksLeftOpenOnTheirLine(t *testing.T) {
+  doc := "[[File:x.jpg Vu [[Victor Hugo]]\n* Suite [sic\n* Fin [[Lien]]"
+  p := Parse(Tokenize(doc))
+  assertEqual(t, "Open link as text", "[[File:x.jpg Vu Victor Hugo\n* Suite [sic\n* Fin Lien", p.StringRepresentation())
+  assertEqual(t, "Open link as text node", NodeText, p[0].Typ)
+  assertEqual(t, "Closed link on the last line", NodeLink, p[len(p)-1].Typ)
+}
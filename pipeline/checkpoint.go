@@ -0,0 +1,226 @@
+package pipeline
+
+import (
+  "context"
+  "encoding/json"
+  "fmt"
+  "github.com/octplane/wikiquote-parser/dump"
+  "io"
+  "io/ioutil"
+  "os"
+  "os/signal"
+  "time"
+)
+
+// Checkpoint is the progress of an extraction, saved by a CheckpointWriter
+//    - Pages counts the dump pages done, the filtered out ones included
+//    - Offset is the size of the output holding the records of these pages
+type Checkpoint struct {
+  Dump       string    `json:"dump"`
+  Output     string    `json:"output"`
+  Offset     int64     `json:"offset"`
+  Pages      int       `json:"pages"`
+  LastPageID int       `json:"last_page_id"`
+  Records    int       `json:"records"`
+  Updated    time.Time `json:"updated"`
+}
+
+// LoadCheckpoint reads a checkpoint saved by Save
+func LoadCheckpoint(path string) (Checkpoint, error) {
+  var c Checkpoint
+  text, err := ioutil.ReadFile(path)
+  if err != nil {
+    return c, err
+  }
+  if err := json.Unmarshal(text, &c); err != nil {
+    return c, fmt.Errorf("pipeline: %s: %s", path, err)
+  }
+  return c, nil
+}
+
+// Save writes the checkpoint next to path and renames it, so that a crash
+// leaves either the previous checkpoint or the new one
+func (c Checkpoint) Save(path string) error {
+  text, err := json.MarshalIndent(c, "", "  ")
+  if err != nil {
+    return err
+  }
+  tmp := path + ".tmp"
+  if err := ioutil.WriteFile(tmp, append(text, '\n'), 0644); err != nil {
+    return err
+  }
+  return os.Rename(tmp, path)
+}
+
+// PageRecords are the CSV records extracted from a dump page, the result
+// expected by a CheckpointWriter. Filtered out pages have no records but must
+// still be written to be counted as done.
+type PageRecords struct {
+  PageID  int
+  Records [][]string
+}
+
+// DefaultCheckpointEvery is the number of pages between two checkpoints
+const DefaultCheckpointEvery = 1000
+
+// CheckpointWriter writes PageRecords to a CSV output and saves a checkpoint
+// every Every pages and when flushed
+type CheckpointWriter struct {
+  Every int
+
+  path    string
+  file    *os.File
+  csv     *CSV
+  state   Checkpoint
+  pending int
+}
+
+// NewCheckpointWriter creates the output of an extraction of the dump, saving
+// its checkpoints to path
+func NewCheckpointWriter(path string, dumpFile string, output string) (*CheckpointWriter, error) {
+  file, err := os.Create(output)
+  if err != nil {
+    return nil, err
+  }
+  c := Checkpoint{Dump: dumpFile, Output: output}
+  return &CheckpointWriter{Every: DefaultCheckpointEvery, path: path, file: file, csv: NewCSV(file), state: c}, nil
+}
+
+// ResumeCheckpointWriter reopens the output of the extraction checkpointed at
+// path and drops what was written after the checkpoint: the pages it comes
+// from are extracted again.
+func ResumeCheckpointWriter(path string, dumpFile string) (*CheckpointWriter, error) {
+  c, err := LoadCheckpoint(path)
+  if err != nil {
+    return nil, err
+  }
+  if c.Dump != dumpFile {
+    return nil, fmt.Errorf("pipeline: %s is a checkpoint of %s, not %s", path, c.Dump, dumpFile)
+  }
+  file, err := os.OpenFile(c.Output, os.O_WRONLY, 0)
+  if err != nil {
+    return nil, err
+  }
+  if err := file.Truncate(c.Offset); err != nil {
+    file.Close()
+    return nil, err
+  }
+  if _, err := file.Seek(c.Offset, io.SeekStart); err != nil {
+    file.Close()
+    return nil, err
+  }
+  return &CheckpointWriter{Every: DefaultCheckpointEvery, path: path, file: file, csv: NewCSV(file), state: c}, nil
+}
+
+// Checkpoint returns the progress written so far
+func (w *CheckpointWriter) Checkpoint() Checkpoint {
+  return w.state
+}
+
+func (w *CheckpointWriter) Write(result interface{}) error {
+  r, ok := result.(PageRecords)
+  if !ok {
+    return fmt.Errorf("pipeline: cannot checkpoint a %T", result)
+  }
+  if err := w.csv.Write(r.Records); err != nil {
+    return err
+  }
+  w.state.Pages += 1
+  w.state.LastPageID = r.PageID
+  w.state.Records += len(r.Records)
+  w.pending += 1
+  if w.pending >= w.Every {
+    return w.Flush()
+  }
+  return nil
+}
+
+// Flush syncs the output to disk before saving the checkpoint
+func (w *CheckpointWriter) Flush() error {
+  if err := w.csv.Flush(); err != nil {
+    return err
+  }
+  if err := w.file.Sync(); err != nil {
+    return err
+  }
+  offset, err := w.file.Seek(0, io.SeekCurrent)
+  if err != nil {
+    return err
+  }
+  w.state.Offset = offset
+  w.state.Updated = time.Now()
+  w.pending = 0
+  return w.state.Save(w.path)
+}
+
+// Close closes the output, without flushing it
+func (w *CheckpointWriter) Close() error {
+  return w.file.Close()
+}
+
+// Skip drops the dump pages done according to c before reading source. It
+// fails when the last of them does not have the ID of the checkpoint, which
+// means the dump changed.
+func Skip(source Source, c Checkpoint) Source {
+  done := 0
+  return func() (interface{}, error) {
+    for done < c.Pages {
+      item, err := source()
+      if err == io.EOF {
+        return nil, fmt.Errorf("pipeline: the dump ends before the %d pages of the checkpoint", c.Pages)
+      }
+      if err != nil {
+        return nil, err
+      }
+      done += 1
+      if p, ok := item.(*dump.Page); ok && done == c.Pages && p.ID != c.LastPageID {
+        return nil, fmt.Errorf("pipeline: page %d of the dump has ID %d, the checkpoint expected %d", done, p.ID, c.LastPageID)
+      }
+    }
+    return source()
+  }
+}
+
+// OnInterrupt ends source at the first SIGINT, so that the pipeline finishes
+// the items already read and flushes its writer, and cancels the returned
+// context at the second one. notify, when not nil, receives a message for
+// each interruption. The returned function stops catching SIGINT.
+func OnInterrupt(ctx context.Context, source Source, notify func(msg string)) (context.Context, Source, func()) {
+  if notify == nil {
+    notify = func(string) {}
+  }
+  ctx, cancel := context.WithCancel(ctx)
+  signals := make(chan os.Signal, 2)
+  signal.Notify(signals, os.Interrupt)
+  stop := make(chan struct{})
+
+  go func() {
+    select {
+    case <-signals:
+      notify("interrupted, finishing the pages being extracted, interrupt again to abort")
+      close(stop)
+    case <-ctx.Done():
+      return
+    }
+    select {
+    case <-signals:
+      notify("interrupted again, aborting")
+      cancel()
+    case <-ctx.Done():
+    }
+  }()
+
+  interruptible := func() (interface{}, error) {
+    select {
+    case <-stop:
+      return nil, io.EOF
+    default:
+      return source()
+    }
+  }
+  release := func() {
+    signal.Stop(signals)
+    cancel()
+  }
+  return ctx, interruptible, release
+}
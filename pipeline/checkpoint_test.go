@@ -0,0 +1,171 @@
+package pipeline
+
+import (
+  "context"
+  "github.com/octplane/wikiquote-parser/dump"
+  "io"
+  "io/ioutil"
+  "os"
+  "path/filepath"
+  "strconv"
+  "syscall"
+  "testing"
+  "time"
+)
+
+// dumpPages is a Source of n pages with IDs 10, 20, 30...
+func dumpPages(n int) Source {
+  i := 0
+  return func() (interface{}, error) {
+    if i >= n {
+      return nil, io.EOF
+    }
+    i += 1
+    return &dump.Page{ID: 10 * i, NS: i % 2, Title: "Page " + strconv.Itoa(i)}, nil
+  }
+}
+
+// extract returns a record per page of the main namespace
+func extract(ctx context.Context, item interface{}) (interface{}, error) {
+  p := item.(*dump.Page)
+  if p.NS != 0 {
+    return PageRecords{PageID: p.ID}, nil
+  }
+  return PageRecords{PageID: p.ID, Records: [][]string{{strconv.Itoa(p.ID), p.Title}}}, nil
+}
+
+func tempDir(t *testing.T) string {
+  dir, err := ioutil.TempDir("", "checkpoint")
+  if err != nil {
+    t.Fatal(err)
+  }
+  return dir
+}
+
+func TestCheckpointWriter(t *testing.T) {
+  dir := tempDir(t)
+  defer os.RemoveAll(dir)
+  path := filepath.Join(dir, "checkpoint.json")
+
+  w, err := NewCheckpointWriter(path, "dump.xml", filepath.Join(dir, "out.csv"))
+  if err != nil {
+    t.Fatal(err)
+  }
+  w.Every = 3
+  p := Pipeline{Source: dumpPages(10), Worker: extract, Writer: w, Workers: 3}
+  if err := p.Run(context.Background()); err != nil {
+    t.Fatal(err)
+  }
+  w.Close()
+
+  c, err := LoadCheckpoint(path)
+  if err != nil {
+    t.Fatal(err)
+  }
+  if c.Pages != 10 || c.LastPageID != 100 || c.Records != 5 || c.Dump != "dump.xml" {
+    t.Errorf("Unexpected checkpoint %+v", c)
+  }
+  text, _ := ioutil.ReadFile(c.Output)
+  if int64(len(text)) != c.Offset || string(text[:12]) != "20,Page 2\n40" {
+    t.Errorf("Unexpected output of %d bytes %q", c.Offset, text)
+  }
+}
+
+func TestResume(t *testing.T) {
+  dir := tempDir(t)
+  defer os.RemoveAll(dir)
+  path := filepath.Join(dir, "checkpoint.json")
+  output := filepath.Join(dir, "out.csv")
+
+  // a first run stopped after 4 pages, with a page written after the last
+  // checkpoint as if it crashed before the next one
+  w, err := NewCheckpointWriter(path, "dump.xml", output)
+  if err != nil {
+    t.Fatal(err)
+  }
+  w.Every = 2
+  next := dumpPages(10)
+  for i := 0; i < 5; i++ {
+    page, _ := next()
+    r, _ := extract(context.Background(), page)
+    if err := w.Write(r); err != nil {
+      t.Fatal(err)
+    }
+  }
+  w.csv.Flush()
+  w.Close()
+
+  if _, err := ResumeCheckpointWriter(path, "other.xml"); err == nil {
+    t.Errorf("Expected an error when resuming from another dump")
+  }
+
+  w, err = ResumeCheckpointWriter(path, "dump.xml")
+  if err != nil {
+    t.Fatal(err)
+  }
+  if w.Checkpoint().Pages != 4 {
+    t.Errorf("Expected to resume after 4 pages, got %+v", w.Checkpoint())
+  }
+  p := Pipeline{Source: Skip(dumpPages(10), w.Checkpoint()), Worker: extract, Writer: w, Workers: 3}
+  if err := p.Run(context.Background()); err != nil {
+    t.Fatal(err)
+  }
+  w.Close()
+
+  // the output is the one of an uninterrupted run
+  w, err = NewCheckpointWriter(filepath.Join(dir, "full.json"), "dump.xml", filepath.Join(dir, "full.csv"))
+  if err != nil {
+    t.Fatal(err)
+  }
+  p = Pipeline{Source: dumpPages(10), Worker: extract, Writer: w}
+  if err := p.Run(context.Background()); err != nil {
+    t.Fatal(err)
+  }
+  w.Close()
+
+  resumed, _ := ioutil.ReadFile(output)
+  full, _ := ioutil.ReadFile(filepath.Join(dir, "full.csv"))
+  if string(resumed) != string(full) {
+    t.Errorf("Expected %q, got %q", full, resumed)
+  }
+  c, _ := LoadCheckpoint(path)
+  if c.Pages != 10 || c.Records != 5 {
+    t.Errorf("Unexpected checkpoint %+v", c)
+  }
+}
+
+func TestSkipChangedDump(t *testing.T) {
+  source := Skip(dumpPages(10), Checkpoint{Pages: 3, LastPageID: 42})
+  if _, err := source(); err == nil {
+    t.Errorf("Expected an error for a page ID not matching the checkpoint")
+  }
+
+  source = Skip(dumpPages(2), Checkpoint{Pages: 3, LastPageID: 30})
+  if _, err := source(); err == nil || err == io.EOF {
+    t.Errorf("Expected an error for a dump shorter than the checkpoint, got %v", err)
+  }
+}
+
+func TestOnInterrupt(t *testing.T) {
+  messages := make(chan string, 2)
+  ctx, source, release := OnInterrupt(context.Background(), dumpPages(100), func(msg string) {
+    messages <- msg
+  })
+  defer release()
+
+  if _, err := source(); err != nil {
+    t.Fatal(err)
+  }
+  syscall.Kill(os.Getpid(), syscall.SIGINT)
+  select {
+  case <-messages:
+  case <-time.After(5 * time.Second):
+    t.Fatal("Expected a message for the interruption")
+  }
+  if _, err := source(); err != io.EOF {
+    t.Errorf("Expected the source to end after an interruption, got %v", err)
+  }
+  if ctx.Err() != nil {
+    t.Errorf("Expected the context to be cancelled at the second interruption only")
+  }
+}
@@ -0,0 +1,262 @@
+// Package pipeline runs the extraction of a dump on several goroutines: a
+// single reader feeds N workers, and a single writer receives their results
+// in the order the reader produced the items.
+package pipeline
+
+import (
+  "context"
+  "encoding/csv"
+  "errors"
+  "fmt"
+  "github.com/octplane/wikiquote-parser/dump"
+  "io"
+  "os"
+  "runtime"
+  "strings"
+  "sync"
+)
+
+// Source returns the next item to process, or io.EOF when there is none left.
+// It is only called from the reader goroutine.
+type Source func() (interface{}, error)
+
+// Worker turns an item into a result. It is called concurrently and should
+// return early when ctx is cancelled. A nil result is not written.
+type Worker func(ctx context.Context, item interface{}) (interface{}, error)
+
+// Writer receives the results from a single goroutine, in source order
+type Writer interface {
+  Write(result interface{}) error
+  Flush() error
+}
+
+// Pipeline connects a Source, Workers goroutines running Worker and a Writer
+//    - Workers defaults to one per CPU
+//    - Buffer bounds the items read ahead of the writer, twice the workers by default
+type Pipeline struct {
+  Source  Source
+  Worker  Worker
+  Writer  Writer
+  Workers int
+  Buffer  int
+}
+
+type job struct {
+  item interface{}
+  out  chan result
+}
+
+type result struct {
+  value interface{}
+  err   error
+}
+
+// Run processes every item of the source and returns once all the goroutines
+// have exited. The first error of the source, a worker or the writer cancels
+// the remaining work and is returned, as is the cancellation of ctx. The
+// writer is flushed in every case, so the results written before an error are
+// kept.
+func (p *Pipeline) Run(ctx context.Context) (err error) {
+  workers := p.Workers
+  if workers < 1 {
+    workers = runtime.NumCPU()
+  }
+  buffer := p.Buffer
+  if buffer < 1 {
+    buffer = 2 * workers
+  }
+
+  ctx, cancel := context.WithCancel(ctx)
+  defer cancel()
+
+  // pending holds the result channels in source order: the writer waits on
+  // them one after the other, and its capacity is the backpressure on the
+  // reader
+  pending := make(chan chan result, buffer)
+  jobs := make(chan job)
+  var wg sync.WaitGroup
+
+  wg.Add(1)
+  go func() {
+    defer wg.Done()
+    defer close(pending)
+    defer close(jobs)
+    for ctx.Err() == nil {
+      item, err := p.Source()
+      if err == io.EOF {
+        return
+      }
+      out := make(chan result, 1)
+      if err != nil {
+        out <- result{err: err}
+      }
+      select {
+      case pending <- out:
+      case <-ctx.Done():
+        return
+      }
+      if err != nil {
+        return
+      }
+      select {
+      case jobs <- job{item, out}:
+      case <-ctx.Done():
+        return
+      }
+    }
+  }()
+
+  for i := 0; i < workers; i++ {
+    wg.Add(1)
+    go func() {
+      defer wg.Done()
+      for j := range jobs {
+        value, err := p.Worker(ctx, j.item)
+        j.out <- result{value, err}
+      }
+    }()
+  }
+
+  err = p.write(ctx, pending)
+  if err == nil {
+    // the reader stops early when the caller cancels ctx
+    err = ctx.Err()
+  }
+  cancel()
+  wg.Wait()
+
+  if ferr := p.Writer.Flush(); err == nil {
+    err = ferr
+  }
+  return err
+}
+
+func (p *Pipeline) write(ctx context.Context, pending chan chan result) error {
+  for out := range pending {
+    var r result
+    select {
+    case r = <-out:
+    case <-ctx.Done():
+      return ctx.Err()
+    }
+    if r.err != nil {
+      return r.err
+    }
+    if r.value == nil {
+      continue
+    }
+    if err := p.Writer.Write(r.value); err != nil {
+      return err
+    }
+  }
+  return nil
+}
+
+// Pages reads the pages of a dump, one item per *dump.Page
+func Pages(r *dump.Reader) Source {
+  return func() (interface{}, error) {
+    return r.Next()
+  }
+}
+
+var errStopped = errors.New("pipeline: source stopped")
+
+// MultistreamPages reads the pages of a multistream dump, one item per
+// *dump.Page, decompressing its streams on workers goroutines as
+// dump.Multistream.Each does. The returned function stops the decompression
+// once the source is no longer read, and waits for it to end.
+func MultistreamPages(m *dump.Multistream, workers int) (Source, func()) {
+  pages := make(chan *dump.Page)
+  end := make(chan error, 1)
+  stop := make(chan struct{})
+  stopped := make(chan struct{})
+  go func() {
+    defer close(stopped)
+    end <- m.Each(workers, func(p *dump.Page) error {
+      select {
+      case pages <- p:
+        return nil
+      case <-stop:
+        return errStopped
+      }
+    })
+    close(pages)
+  }()
+
+  var err error
+  source := func() (interface{}, error) {
+    if err != nil {
+      return nil, err
+    }
+    if p, ok := <-pages; ok {
+      return p, nil
+    }
+    if err = <-end; err == nil {
+      err = io.EOF
+    }
+    return nil, err
+  }
+  var once sync.Once
+  return source, func() {
+    once.Do(func() { close(stop) })
+    <-stopped
+  }
+}
+
+// OpenPages opens a dump and returns the source of its pages with its site
+// info. A .xml.bz2 multistream dump with its index next to it is
+// decompressed on workers goroutines, the other dumps are read on the source
+// goroutine. The returned function stops the reading and closes the dump.
+func OpenPages(path string, workers int) (Source, *dump.SiteInfo, func() error, error) {
+  if _, err := os.Stat(dump.IndexPath(path)); err == nil && strings.HasSuffix(path, ".xml.bz2") {
+    m, err := dump.OpenMultistream(path, dump.IndexPath(path))
+    if err != nil {
+      return nil, nil, nil, err
+    }
+    si, err := m.SiteInfo()
+    if err != nil {
+      m.Close()
+      return nil, nil, nil, err
+    }
+    source, stop := MultistreamPages(m, workers)
+    return source, si, func() error {
+      stop()
+      return m.Close()
+    }, nil
+  }
+
+  r, err := dump.Open(path)
+  if err != nil {
+    return nil, nil, nil, err
+  }
+  return Pages(r), r.SiteInfo(), r.Close, nil
+}
+
+// CSV writes results holding [][]string records
+type CSV struct {
+  w *csv.Writer
+}
+
+// NewCSV returns a Writer of CSV records to w
+func NewCSV(w io.Writer) *CSV {
+  return &CSV{w: csv.NewWriter(w)}
+}
+
+func (c *CSV) Write(result interface{}) error {
+  records, ok := result.([][]string)
+  if !ok {
+    return fmt.Errorf("pipeline: CSV cannot write a %T", result)
+  }
+  for _, record := range records {
+    if err := c.w.Write(record); err != nil {
+      return err
+    }
+  }
+  return nil
+}
+
+// Flush writes the buffered records and returns the first write error
+func (c *CSV) Flush() error {
+  c.w.Flush()
+  return c.w.Error()
+}
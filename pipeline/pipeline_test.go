@@ -0,0 +1,260 @@
+package pipeline
+
+import (
+  "bytes"
+  "context"
+  "errors"
+  "github.com/octplane/wikiquote-parser/dump"
+  "io"
+  "strconv"
+  "strings"
+  "sync"
+  "testing"
+  "time"
+)
+
+// counter is a Source of the integers below n
+func counter(n int) Source {
+  i := 0
+  return func() (interface{}, error) {
+    if i >= n {
+      return nil, io.EOF
+    }
+    i += 1
+    return i - 1, nil
+  }
+}
+
+// collector is a Writer keeping the results
+type collector struct {
+  values  []int
+  flushed int
+  err     error
+}
+
+func (c *collector) Write(result interface{}) error {
+  c.values = append(c.values, result.(int))
+  return c.err
+}
+
+func (c *collector) Flush() error {
+  c.flushed += 1
+  return nil
+}
+
+// slow makes the first items the slowest ones so that they complete out of order
+func slow(ctx context.Context, item interface{}) (interface{}, error) {
+  i := item.(int)
+  time.Sleep(time.Duration(20-i%20) * 100 * time.Microsecond)
+  return i, nil
+}
+
+func TestRunInOrder(t *testing.T) {
+  c := &collector{}
+  p := Pipeline{Source: counter(100), Worker: slow, Writer: c, Workers: 8}
+  if err := p.Run(context.Background()); err != nil {
+    t.Fatal(err)
+  }
+  if len(c.values) != 100 {
+    t.Fatalf("Expected 100 results, got %d", len(c.values))
+  }
+  for ix, v := range c.values {
+    if v != ix {
+      t.Fatalf("Expected %d at %d, got %d", ix, ix, v)
+    }
+  }
+  if c.flushed != 1 {
+    t.Errorf("Expected one flush, got %d", c.flushed)
+  }
+}
+
+func TestRunSkipsNilResults(t *testing.T) {
+  c := &collector{}
+  odd := func(ctx context.Context, item interface{}) (interface{}, error) {
+    if item.(int)%2 == 0 {
+      return nil, nil
+    }
+    return item, nil
+  }
+  p := Pipeline{Source: counter(10), Worker: odd, Writer: c, Workers: 3}
+  if err := p.Run(context.Background()); err != nil {
+    t.Fatal(err)
+  }
+  if len(c.values) != 5 || c.values[0] != 1 || c.values[4] != 9 {
+    t.Errorf("Unexpected results %v", c.values)
+  }
+}
+
+func TestRunWorkerError(t *testing.T) {
+  failure := errors.New("failure")
+  c := &collector{}
+  failing := func(ctx context.Context, item interface{}) (interface{}, error) {
+    if item.(int) == 42 {
+      return nil, failure
+    }
+    return item, nil
+  }
+  p := Pipeline{Source: counter(1000), Worker: failing, Writer: c, Workers: 4}
+  if err := p.Run(context.Background()); err != failure {
+    t.Errorf("Expected the worker error, got %v", err)
+  }
+  // the results before the failing item are written and flushed
+  if len(c.values) != 42 || c.flushed != 1 {
+    t.Errorf("Unexpected %d results and %d flushes", len(c.values), c.flushed)
+  }
+}
+
+func TestRunSourceError(t *testing.T) {
+  failure := errors.New("truncated dump")
+  next := counter(10)
+  source := func() (interface{}, error) {
+    item, err := next()
+    if err == io.EOF {
+      return nil, failure
+    }
+    return item, err
+  }
+  c := &collector{}
+  p := Pipeline{Source: source, Worker: slow, Writer: c, Workers: 4}
+  if err := p.Run(context.Background()); err != failure {
+    t.Errorf("Expected the source error, got %v", err)
+  }
+  if len(c.values) != 10 {
+    t.Errorf("Expected the 10 items read before the error, got %v", c.values)
+  }
+}
+
+func TestRunWriterError(t *testing.T) {
+  failure := errors.New("disk full")
+  c := &collector{err: failure}
+  p := Pipeline{Source: counter(1000), Worker: slow, Writer: c, Workers: 4}
+  if err := p.Run(context.Background()); err != failure {
+    t.Errorf("Expected the writer error, got %v", err)
+  }
+  if len(c.values) != 1 {
+    t.Errorf("Expected the writer to stop at the first error, got %v", c.values)
+  }
+}
+
+func TestRunCancel(t *testing.T) {
+  ctx, cancel := context.WithCancel(context.Background())
+  c := &collector{}
+  cancelling := func(ctx context.Context, item interface{}) (interface{}, error) {
+    if item.(int) == 10 {
+      cancel()
+    }
+    return item, nil
+  }
+  p := Pipeline{Source: counter(1000000), Worker: cancelling, Writer: c, Workers: 4}
+  if err := p.Run(ctx); err != context.Canceled {
+    t.Errorf("Expected the cancellation, got %v", err)
+  }
+  if len(c.values) >= 1000000 || c.flushed != 1 {
+    t.Errorf("Unexpected %d results and %d flushes", len(c.values), c.flushed)
+  }
+}
+
+func TestRunBackpressure(t *testing.T) {
+  var mutex sync.Mutex
+  read, written, ahead := 0, 0, 0
+  next := counter(200)
+  source := func() (interface{}, error) {
+    mutex.Lock()
+    defer mutex.Unlock()
+    read += 1
+    if read-written > ahead {
+      ahead = read - written
+    }
+    return next()
+  }
+  w := writerFunc(func(result interface{}) error {
+    mutex.Lock()
+    defer mutex.Unlock()
+    written += 1
+    time.Sleep(100 * time.Microsecond)
+    return nil
+  })
+  p := Pipeline{Source: source, Worker: slow, Writer: w, Workers: 4, Buffer: 4}
+  if err := p.Run(context.Background()); err != nil {
+    t.Fatal(err)
+  }
+  // the buffered items, the one being handed to a worker and the one being read
+  if ahead > p.Buffer+2 {
+    t.Errorf("The reader went %d items ahead of the writer", ahead)
+  }
+}
+
+type writerFunc func(result interface{}) error
+
+func (f writerFunc) Write(result interface{}) error {
+  return f(result)
+}
+
+func (f writerFunc) Flush() error {
+  return nil
+}
+
+func TestCSV(t *testing.T) {
+  var buf bytes.Buffer
+  rows := func(ctx context.Context, item interface{}) (interface{}, error) {
+    i := strconv.Itoa(item.(int))
+    return [][]string{{i, "a"}, {i, "b, c"}}, nil
+  }
+  p := Pipeline{Source: counter(2), Worker: rows, Writer: NewCSV(&buf)}
+  if err := p.Run(context.Background()); err != nil {
+    t.Fatal(err)
+  }
+  expected := strings.Join([]string{"0,a", `0,"b, c"`, "1,a", `1,"b, c"`, ""}, "\n")
+  if buf.String() != expected {
+    t.Errorf("Expected %q, got %q", expected, buf.String())
+  }
+
+  if err := NewCSV(&buf).Write("text"); err == nil {
+    t.Errorf("Expected an error for a result that is not records")
+  }
+}
+
+const multistreamDump = "../dump/testdata/multistream.xml.bz2"
+
+func TestOpenPages(t *testing.T) {
+  for _, workers := range []int{1, 4} {
+    source, si, stop, err := OpenPages(multistreamDump, workers)
+    if err != nil {
+      t.Fatal(err)
+    }
+    if si == nil || si.DBName == "" {
+      t.Errorf("Unexpected site info %+v", si)
+    }
+    ids := make([]string, 0)
+    for {
+      item, err := source()
+      if err == io.EOF {
+        break
+      }
+      if err != nil {
+        t.Fatal(err)
+      }
+      ids = append(ids, strconv.Itoa(item.(*dump.Page).ID))
+    }
+    if strings.Join(ids, ",") != "10,11,12,13,14,15,16" {
+      t.Errorf("Unexpected pages with %d workers: %v", workers, ids)
+    }
+    if err := stop(); err != nil {
+      t.Error(err)
+    }
+  }
+}
+
+func TestOpenPagesStop(t *testing.T) {
+  source, _, stop, err := OpenPages(multistreamDump, 2)
+  if err != nil {
+    t.Fatal(err)
+  }
+  if item, err := source(); err != nil || item.(*dump.Page).ID != 10 {
+    t.Fatalf("Unexpected first page %v %v", item, err)
+  }
+  // the decompression of the following streams is abandoned
+  if err := stop(); err != nil {
+    t.Error(err)
+  }
+}
@@ -0,0 +1,91 @@
+package pipeline
+
+import (
+  "bufio"
+  "encoding/json"
+  "fmt"
+  "github.com/octplane/wikiquote-parser/dump"
+  "os"
+  "sync"
+  "time"
+)
+
+// Quarantined is a page the extraction gave up on
+type Quarantined struct {
+  ID     int       `json:"id"`
+  Title  string    `json:"title"`
+  NS     int       `json:"ns"`
+  Reason string    `json:"reason"`
+  Time   time.Time `json:"time"`
+}
+
+// Quarantine appends the pages the workers give up on to a file, one JSON
+// object per line, so that the extraction can go on. It can be used from
+// several goroutines.
+type Quarantine struct {
+  mutex sync.Mutex
+  file  *os.File
+  count int
+}
+
+// OpenQuarantine opens the quarantine file at path, appending to it
+func OpenQuarantine(path string) (*Quarantine, error) {
+  file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+  if err != nil {
+    return nil, err
+  }
+  return &Quarantine{file: file}, nil
+}
+
+// Add records the page with the reason it was given up on
+func (q *Quarantine) Add(p *dump.Page, reason string) error {
+  line, err := json.Marshal(Quarantined{ID: p.ID, Title: p.Title, NS: p.NS, Reason: reason, Time: time.Now()})
+  if err != nil {
+    return err
+  }
+  q.mutex.Lock()
+  defer q.mutex.Unlock()
+  q.count += 1
+  _, err = q.file.Write(append(line, '\n'))
+  return err
+}
+
+// Len returns the number of pages added since the quarantine was opened
+func (q *Quarantine) Len() int {
+  q.mutex.Lock()
+  defer q.mutex.Unlock()
+  return q.count
+}
+
+func (q *Quarantine) Close() error {
+  return q.file.Close()
+}
+
+// ReadQuarantine returns the pages of a quarantine file in the order they
+// were first added. A page added again, by a resumed extraction, keeps its
+// last reason.
+func ReadQuarantine(path string) ([]Quarantined, error) {
+  file, err := os.Open(path)
+  if err != nil {
+    return nil, err
+  }
+  defer file.Close()
+
+  ret := make([]Quarantined, 0)
+  seen := make(map[int]int)
+  scanner := bufio.NewScanner(file)
+  scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+  for line := 1; scanner.Scan(); line++ {
+    var q Quarantined
+    if err := json.Unmarshal(scanner.Bytes(), &q); err != nil {
+      return nil, fmt.Errorf("pipeline: %s:%d: %s", path, line, err)
+    }
+    if ix, ok := seen[q.ID]; ok {
+      ret[ix] = q
+      continue
+    }
+    seen[q.ID] = len(ret)
+    ret = append(ret, q)
+  }
+  return ret, scanner.Err()
+}
@@ -0,0 +1,61 @@
+package pipeline
+
+import (
+  "github.com/octplane/wikiquote-parser/dump"
+  "os"
+  "path/filepath"
+  "sync"
+  "testing"
+)
+
+func TestQuarantine(t *testing.T) {
+  dir := tempDir(t)
+  defer os.RemoveAll(dir)
+  path := filepath.Join(dir, "quotes.quarantine")
+
+  q, err := OpenQuarantine(path)
+  if err != nil {
+    t.Fatal(err)
+  }
+  var wg sync.WaitGroup
+  for i := 1; i <= 10; i++ {
+    wg.Add(1)
+    go func(i int) {
+      defer wg.Done()
+      if err := q.Add(&dump.Page{ID: i, Title: "Page"}, "panic: index out of range"); err != nil {
+        t.Error(err)
+      }
+    }(i)
+  }
+  wg.Wait()
+  if q.Len() != 10 {
+    t.Errorf("Expected 10 pages, got %d", q.Len())
+  }
+  q.Close()
+
+  // a resumed extraction appends to the file
+  q, err = OpenQuarantine(path)
+  if err != nil {
+    t.Fatal(err)
+  }
+  q.Add(&dump.Page{ID: 3, Title: "Page"}, "parse budget exceeded")
+  q.Add(&dump.Page{ID: 42, NS: 102, Title: "Auteur:Alain"}, "parse budget exceeded")
+  q.Close()
+
+  pages, err := ReadQuarantine(path)
+  if err != nil {
+    t.Fatal(err)
+  }
+  if len(pages) != 11 {
+    t.Fatalf("Expected 11 pages, got %+v", pages)
+  }
+  for _, p := range pages {
+    if p.ID == 3 && p.Reason != "parse budget exceeded" {
+      t.Errorf("Expected the last reason of page 3, got %q", p.Reason)
+    }
+  }
+  last := pages[10]
+  if last.ID != 42 || last.NS != 102 || last.Title != "Auteur:Alain" || last.Time.IsZero() {
+    t.Errorf("Unexpected page %+v", last)
+  }
+}
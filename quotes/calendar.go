@@ -0,0 +1,150 @@
+package quotes
+
+import (
+  "bufio"
+  "encoding/json"
+  "fmt"
+  . "github.com/octplane/wikiquote-parser"
+  "io"
+  "sort"
+  "strings"
+  "time"
+)
+
+const dayLayout = "2006-01-02"
+
+// featuredTemplate tells whether a template call features the quote before it
+func (p *Profile) featuredTemplate(n Node) bool {
+  _, ok := p.featured[CanonicalTemplateName(n.StringParamOrEmpty("name"))]
+  return ok
+}
+
+// featuredDate reads the day of a featured template, the month being a name
+// in French or English
+func (p *Profile) featuredDate(n Node) (time.Time, error) {
+  day, month, year := p.param(n, "day"), p.param(n, "month"), p.param(n, "year")
+  if day == "" || month == "" || year == "" {
+    return time.Time{}, fmt.Errorf("incomplete date %q %q %q", day, month, year)
+  }
+  return ParseDate(day + " " + month + " " + year)
+}
+
+// feature adds the day of a featured template to the quote at index ix
+func (e *extraction) feature(n Node, ix int) {
+  name := n.StringParamOrEmpty("name")
+  day, err := e.profile.featuredDate(n)
+  if err != nil {
+    e.reject(ReasonInvalidFeatured, n.Span, "", "{{%s}}: %s", name, err)
+    return
+  }
+  if ix < 0 {
+    e.reject(ReasonInvalidFeatured, n.Span, "", "{{%s}} of %s does not follow a quote", name, day.Format(dayLayout))
+    return
+  }
+  e.quotes[ix].Featured = append(e.quotes[ix].Featured, day)
+}
+
+// Calendar gathers the featured quotes by day
+type Calendar struct {
+  days map[string][]Quote
+}
+
+// Day holds the quotes featured on a day
+type Day struct {
+  Date   string  `json:"date"`
+  Quotes []Quote `json:"quotes"`
+}
+
+func NewCalendar() *Calendar {
+  return &Calendar{days: make(map[string][]Quote)}
+}
+
+// Add puts the quote on every day it was featured
+func (c *Calendar) Add(q Quote) {
+  for _, d := range q.Featured {
+    key := d.Format(dayLayout)
+    c.days[key] = append(c.days[key], q)
+  }
+}
+
+// Days returns the days having quotes, in chronological order
+func (c *Calendar) Days() []Day {
+  keys := make([]string, 0, len(c.days))
+  for k := range c.days {
+    keys = append(keys, k)
+  }
+  sort.Strings(keys)
+
+  ret := make([]Day, 0, len(keys))
+  for _, k := range keys {
+    ret = append(ret, Day{Date: k, Quotes: c.days[k]})
+  }
+  return ret
+}
+
+func (c *Calendar) WriteJSON(w io.Writer) error {
+  enc := json.NewEncoder(w)
+  enc.SetIndent("", "  ")
+  return enc.Encode(c.Days())
+}
+
+// WriteICS writes an iCalendar file with an all day event per featured
+// quote. The events are stamped with their day so that the output only
+// depends on the quotes.
+func (c *Calendar) WriteICS(w io.Writer) error {
+  bw := bufio.NewWriter(w)
+  line := func(name string, value string) {
+    writeICSLine(bw, name+":"+value)
+  }
+
+  line("BEGIN", "VCALENDAR")
+  line("VERSION", "2.0")
+  line("PRODID", "-//octplane//wikiquote-parser//EN")
+  for _, d := range c.Days() {
+    day := strings.Replace(d.Date, "-", "", -1)
+    for _, q := range d.Quotes {
+      line("BEGIN", "VEVENT")
+      line("UID", q.ID+"-"+day+"@wikiquote-parser")
+      line("DTSTAMP", day+"T000000Z")
+      line("DTSTART;VALUE=DATE", day)
+      line("SUMMARY", icsText(q.Quote))
+      line("DESCRIPTION", icsText(attribution(q)))
+      line("END", "VEVENT")
+    }
+  }
+  line("END", "VCALENDAR")
+  return bw.Flush()
+}
+
+// attribution is the author, source and page of a quote, as one line
+func attribution(q Quote) string {
+  parts := make([]string, 0, 3)
+  for _, p := range []string{q.Author, q.Source.Title, q.PageTitle} {
+    if p != "" {
+      parts = append(parts, p)
+    }
+  }
+  return strings.Join(parts, ", ")
+}
+
+// icsText escapes a TEXT value of iCalendar
+func icsText(s string) string {
+  return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// writeICSLine writes a content line folded every 75 bytes, without cutting
+// an UTF-8 character
+func writeICSLine(w *bufio.Writer, s string) {
+  max := 75
+  for len(s) > max {
+    cut := max
+    for cut > 0 && s[cut]&0xC0 == 0x80 {
+      cut -= 1
+    }
+    w.WriteString(s[:cut] + "\r\n ")
+    s = s[cut:]
+    // the leading space of the continuation counts
+    max = 74
+  }
+  w.WriteString(s + "\r\n")
+}
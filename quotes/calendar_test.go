@@ -0,0 +1,89 @@
+package quotes
+
+import (
+  "bytes"
+  "encoding/json"
+  . "github.com/octplane/wikiquote-parser"
+  "strings"
+  "testing"
+  "time"
+)
+
+const featured = `{{citation|citation=Aimer, c’est trouver sa richesse hors de soi.}}
+{{Réf Livre|titre=Éléments de philosophie|auteur=Alain}}
+{{Choisie citation du jour|puce=*|année=2010|mois=mars|jour=1|commentaire=|}}
+{{Choisie citation du jour
+|année=2012
+|mois=février
+|jour=1er
+}}
+{{citation|Le cœur a ses raisons que la raison ne connaît point.}}
+{{Choisie citation du jour|année=2011|mois=brumaire|jour=3}}
+{{Réf Livre|titre=Pensées|auteur=Blaise Pascal}}
+{{Choisie citation du jour|année=2009|mois=août|jour=30}}`
+
+func TestFeatured(t *testing.T) {
+  found, rejections := Extract(Page{Title: "Amour", Language: "fr"}, Parse(Tokenize(featured)))
+  if len(found) != 2 || len(rejections) != 1 || rejections[0].Reason != ReasonInvalidFeatured {
+    t.Fatalf("Unexpected extraction %+v %+v", found, rejections)
+  }
+
+  expected := []time.Time{time.Date(2010, time.March, 1, 0, 0, 0, 0, time.UTC), time.Date(2012, time.February, 1, 0, 0, 0, 0, time.UTC)}
+  if len(found[0].Featured) != 2 || !found[0].Featured[0].Equal(expected[0]) || !found[0].Featured[1].Equal(expected[1]) {
+    t.Errorf("Expected %v, got %v", expected, found[0].Featured)
+  }
+  if found[0].Record()[9] != "2010-03-01 2012-02-01" {
+    t.Errorf("Unexpected record %v", found[0].Record())
+  }
+  if len(found[1].Featured) != 1 || found[1].Featured[0].Format(dayLayout) != "2009-08-30" {
+    t.Errorf("Unexpected featured days %v", found[1].Featured)
+  }
+}
+
+func TestFeaturedWithoutQuote(t *testing.T) {
+  _, rejections := Extract(Page{Title: "Amour", Language: "fr"}, Parse(Tokenize(`{{Choisie citation du jour|année=2010|mois=mars|jour=1}}`)))
+  if len(rejections) != 1 || !strings.Contains(rejections[0].Message, "does not follow a quote") {
+    t.Errorf("Unexpected rejections %+v", rejections)
+  }
+}
+
+func TestCalendar(t *testing.T) {
+  found, _ := Extract(Page{Title: "Amour", Language: "fr"}, Parse(Tokenize(featured)))
+  c := NewCalendar()
+  for _, q := range found {
+    c.Add(q)
+  }
+
+  var b bytes.Buffer
+  if err := c.WriteJSON(&b); err != nil {
+    t.Fatal(err)
+  }
+  days := make([]Day, 0)
+  if err := json.Unmarshal(b.Bytes(), &days); err != nil {
+    t.Fatal(err)
+  }
+  dates := make([]string, 0)
+  for _, d := range days {
+    dates = append(dates, d.Date)
+  }
+  if strings.Join(dates, ",") != "2009-08-30,2010-03-01,2012-02-01" || days[1].Quotes[0].Author != "Alain" {
+    t.Errorf("Unexpected calendar %s", b.String())
+  }
+
+  b.Reset()
+  if err := c.WriteICS(&b); err != nil {
+    t.Fatal(err)
+  }
+  ics := b.String()
+  if strings.Count(ics, "BEGIN:VEVENT") != 3 || !strings.Contains(ics, "DTSTART;VALUE=DATE:20100301\r\n") {
+    t.Errorf("Unexpected calendar %s", ics)
+  }
+  if !strings.Contains(ics, "DESCRIPTION:Alain\\, Éléments de philosophie\\, Amour\r\n") {
+    t.Errorf("Expected an escaped description in %s", ics)
+  }
+  for _, l := range strings.Split(ics, "\r\n") {
+    if len(l) > 75 {
+      t.Errorf("Expected folded lines, got %q", l)
+    }
+  }
+}
@@ -0,0 +1,101 @@
+package quotes
+
+import (
+  . "github.com/octplane/wikiquote-parser"
+  "regexp"
+  "strings"
+)
+
+// Turn is a line of a dialogue. Direction is the stage direction between the
+// speaker and the line, as in {{Personnage|Andrea}}, ''à voix haute'' : ...
+type Turn struct {
+  Speaker   string `json:"speaker"`
+  Direction string `json:"direction,omitempty"`
+  Line      string `json:"line"`
+}
+
+var (
+  poemTag = regexp.MustCompile(`(?i)</?poem\s*>`)
+  brTag   = regexp.MustCompile(`(?i)<br\s*/?>`)
+)
+
+// turns cuts the text of a quote at its speaker templates. It returns nothing
+// when the quote has no speaker.
+func (e *extraction) turns(ns Nodes) []Turn {
+  ret := make([]Turn, 0)
+  var speaker *Node
+  line := make(Nodes, 0)
+  flush := func() {
+    if speaker != nil {
+      ret = append(ret, turn(speaker.StringRepresentation(), line.StringRepresentation()))
+    }
+    line = make(Nodes, 0)
+  }
+
+  for _, n := range ns {
+    if n.Typ == NodeTemplate && e.profile.speakerTemplate(n) {
+      flush()
+      n := n
+      speaker = &n
+      continue
+    }
+    line = append(line, n)
+  }
+  flush()
+  return ret
+}
+
+// turn cleans the text following a speaker: the markup of the poems, the
+// separator and the stage direction before it
+func turn(speaker string, text string) Turn {
+  text = brTag.ReplaceAllString(poemTag.ReplaceAllString(text, ""), "\n")
+  text = strings.TrimSpace(text)
+  t := Turn{Speaker: strings.TrimSpace(speaker)}
+  switch {
+  case strings.HasPrefix(text, ":"):
+    text = text[1:]
+  case strings.HasPrefix(text, ","):
+    if ix := strings.Index(text, ":"); ix > 0 {
+      t.Direction = strings.Trim(text[1:ix], " '")
+      text = text[ix+1:]
+    }
+  }
+  t.Line = strings.TrimSpace(text)
+  return t
+}
+
+// dialogueText renders the turns one per line, as "Speaker : line"
+func dialogueText(turns []Turn) string {
+  lines := make([]string, 0, len(turns))
+  for _, t := range turns {
+    lines = append(lines, t.Speaker+" : "+t.Line)
+  }
+  return strings.Join(lines, "\n")
+}
+
+// addDialogue adds a quote whose text may be a dialogue. A single turn is
+// the quote of its speaker, more turns are an exchange, followed by each of
+// its lines with SplitDialogues.
+func (e *extraction) addDialogue(q Quote, ns Nodes) {
+  turns := e.turns(ns)
+  switch len(turns) {
+  case 0:
+    e.add(q)
+    return
+  case 1:
+    q.Quote, q.Speaker = turns[0].Line, turns[0].Speaker
+    e.add(q)
+    return
+  }
+
+  exchange := q
+  exchange.Quote, exchange.Dialogue = dialogueText(turns), turns
+  e.add(exchange)
+  if !e.options.SplitDialogues {
+    return
+  }
+  for _, t := range turns {
+    q.Quote, q.Speaker = t.Line, t.Speaker
+    e.add(q)
+  }
+}
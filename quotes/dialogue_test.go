@@ -0,0 +1,57 @@
+package quotes
+
+import (
+  . "github.com/octplane/wikiquote-parser"
+  "reflect"
+  "testing"
+)
+
+const dialogue = `{{citation|1=
+{{Personnage|Andrea}}, ''à voix haute'' : Malheureux le pays qui n'a pas de héros !<br/>
+{{Personnage|Galilée}} : Non. Malheureux le pays qui a besoin de héros.
+}}
+{{Réf Livre|titre=La Vie de Galilée|auteur=Bertolt Brecht|année=1939}}
+{{citation|{{Personnage|Le Pacha}} : Quand on mettra les cons sur orbite, t'as pas fini de tourner.}}
+{{Réf Film|titre=Le Pacha|auteur=Michel Audiard|date=1968}}`
+
+func TestDialogues(t *testing.T) {
+  found, rejections := Extract(Page{Title: "Héros", Language: "fr"}, Parse(Tokenize(dialogue)))
+  if len(found) != 2 || len(rejections) != 0 {
+    t.Fatalf("Unexpected extraction %+v %+v", found, rejections)
+  }
+
+  expected := []Turn{
+    {Speaker: "Andrea", Direction: "à voix haute", Line: "Malheureux le pays qui n'a pas de héros !"},
+    {Speaker: "Galilée", Line: "Non. Malheureux le pays qui a besoin de héros."},
+  }
+  if !reflect.DeepEqual(found[0].Dialogue, expected) {
+    t.Errorf("Expected %+v, got %+v", expected, found[0].Dialogue)
+  }
+  if found[0].Quote != "Andrea : Malheureux le pays qui n'a pas de héros !\nGalilée : Non. Malheureux le pays qui a besoin de héros." {
+    t.Errorf("Unexpected exchange %q", found[0].Quote)
+  }
+  if found[0].Author != "Bertolt Brecht" || found[0].Speaker != "" {
+    t.Errorf("Unexpected exchange attribution %+v", found[0])
+  }
+
+  if q := found[1]; q.Speaker != "Le Pacha" || q.Quote != "Quand on mettra les cons sur orbite, t'as pas fini de tourner." || q.Dialogue != nil {
+    t.Errorf("Unexpected single line %+v", q)
+  }
+  if found[1].Source.Type != SourceFilm {
+    t.Errorf("Unexpected source %+v", found[1].Source)
+  }
+}
+
+func TestSplitDialogues(t *testing.T) {
+  found, _ := Profiles["fr"].ExtractWithOptions(Page{Title: "Héros"}, Parse(Tokenize(dialogue)), ExtractOptions{SplitDialogues: true})
+  speakers := make([]string, 0)
+  for _, q := range found {
+    speakers = append(speakers, q.Speaker)
+  }
+  if !reflect.DeepEqual(speakers, []string{"", "Andrea", "Galilée", "Le Pacha"}) {
+    t.Fatalf("Unexpected speakers %v", speakers)
+  }
+  if found[2].Quote != "Non. Malheureux le pays qui a besoin de héros." || found[2].Author != "Bertolt Brecht" || found[2].Source.Title != "La Vie de Galilée" {
+    t.Errorf("Unexpected line %+v", found[2])
+  }
+}
@@ -0,0 +1,334 @@
+package quotes
+
+import (
+  "fmt"
+  . "github.com/octplane/wikiquote-parser"
+  "strings"
+)
+
+// Extract returns the quotes of a page, and the candidates it rejected with
+// the reason, using the profile of the page language
+func Extract(page Page, nodes Nodes) ([]Quote, []Rejection) {
+  p, ok := Profiles[page.Language]
+  if !ok {
+    e := newExtraction(nil, page)
+    e.reject(ReasonUnsupportedLanguage, Span{}, "", "no profile for language %q", page.Language)
+    return e.quotes, e.rejections
+  }
+  return p.Extract(page, nodes)
+}
+
+// ExtractOptions change what is extracted from the pages
+//    - SplitDialogues emits every line of a dialogue as a quote of its speaker, after the whole exchange
+type ExtractOptions struct {
+  SplitDialogues bool
+}
+
+// Extract returns the quotes of a page, and the candidates it rejected with
+// the reason. The page language is the profile one when empty.
+func (p *Profile) Extract(page Page, nodes Nodes) ([]Quote, []Rejection) {
+  return p.ExtractWithOptions(page, nodes, ExtractOptions{})
+}
+
+// ExtractWithOptions is Extract with options
+func (p *Profile) ExtractWithOptions(page Page, nodes Nodes, opts ExtractOptions) ([]Quote, []Rejection) {
+  if page.Language == "" {
+    page.Language = p.Language
+  }
+  e := newExtraction(p, page)
+  e.options = opts
+  e.categories = categories(nodes, p)
+
+  switch p.Strategy {
+  case StrategyTemplates:
+    e.templates(nodes)
+  case StrategyList:
+    e.list(nodes)
+  }
+  return e.quotes, e.rejections
+}
+
+type extraction struct {
+  page       Page
+  profile    *Profile
+  options    ExtractOptions
+  categories []string
+  quotes     []Quote
+  rejections []Rejection
+}
+
+func newExtraction(p *Profile, page Page) *extraction {
+  return &extraction{page: page, profile: p, quotes: make([]Quote, 0), rejections: make([]Rejection, 0)}
+}
+
+func (e *extraction) reject(reason Reason, span Span, quote string, format string, params ...interface{}) {
+  e.rejections = append(e.rejections, Rejection{
+    PageID:    e.page.ID,
+    PageTitle: e.page.Title,
+    Reason:    reason,
+    Message:   fmt.Sprintf(format, params...),
+    Quote:     quote,
+    Span:      span,
+  })
+}
+
+func (e *extraction) add(q Quote) {
+  if strings.TrimSpace(q.Quote) == "" {
+    e.reject(ReasonEmptyQuote, q.Span, "", "the quote is empty")
+    return
+  }
+  q.ID = quoteID(q.Quote)
+  q.Language = e.page.Language
+  q.PageID = e.page.ID
+  q.PageTitle = e.page.Title
+  q.Categories = e.categories
+  e.quotes = append(e.quotes, q)
+}
+
+// categories lists the category links of the page, without their namespace
+func categories(nodes Nodes, p *Profile) []string {
+  ret := make([]string, 0)
+  for _, n := range Flatten(nodes) {
+    if n.Typ != NodeLink {
+      continue
+    }
+    if c, ok := p.category(n); ok {
+      ret = append(ret, c)
+    }
+  }
+  return ret
+}
+
+func contains(names []string, name string) bool {
+  for _, n := range names {
+    if n == name {
+      return true
+    }
+  }
+  return false
+}
+
+// templates pairs every quote template with the closest source template,
+// looking inside the layout templates such as {{début cadre}}. A quote
+// followed by another quote before any source is rejected. The featured
+// templates date the quote paired just before them.
+func (e *extraction) templates(nodes Nodes) {
+  var quote, source *Node
+  var quoteTemplate, sourceTemplate *Template
+  // index of the last paired quote, -1 when a featured template has nothing to date
+  last := -1
+
+  for _, n := range Flatten(nodes) {
+    if n.Typ != NodeTemplate {
+      continue
+    }
+    if t, ok := e.profile.quoteTemplate(n); ok {
+      if quote != nil {
+        e.reject(ReasonNoSource, quote.Span, e.quoteText(*quote), "{{%s}} is not followed by a source", quoteTemplate.Name)
+      }
+      n := n
+      quote, quoteTemplate = &n, t
+      last = -1
+    } else if t, ok := e.profile.sourceTemplate(n); ok {
+      n := n
+      source, sourceTemplate = &n, t
+    } else if e.profile.featuredTemplate(n) {
+      e.feature(n, last)
+      continue
+    } else {
+      continue
+    }
+
+    if quote != nil && source != nil {
+      text, _ := FirstParam(*quote, e.profile.Params["quote"]...)
+      last = len(e.quotes)
+      e.addDialogue(Quote{
+        Author: e.profile.templateParam(*source, sourceTemplate, "author"),
+        Quote:  e.quoteText(*quote),
+        Source: e.profile.templateSource(*source, sourceTemplate),
+        Span:   quote.Span,
+      }, text)
+      if last == len(e.quotes) {
+        last = -1
+      }
+      quote, source = nil, nil
+    }
+  }
+
+  if quote != nil {
+    e.reject(ReasonNoSource, quote.Span, e.quoteText(*quote), "{{%s}} is not followed by a source", quoteTemplate.Name)
+  }
+}
+
+// quoteText is the quote parameter of a quote template
+func (e *extraction) quoteText(n Node) string {
+  return e.profile.param(n, "quote")
+}
+
+// list reads the quotes of the * items. The author and the source are on an
+// item under the quote, or follow a ~ at the end of it. *: items carry on the
+// quote.
+//
+//    - quote
+//     *: rest of the quote
+//     ** [[Author]], source
+//     ** {{cite book|author=Author|title=Source}}
+//    - quote ~ [[Author]], source
+func (e *extraction) list(nodes Nodes) {
+  for _, it := range items(nodes) {
+    if it.prefix == "*" && !contains(e.profile.SkipSections, it.section) {
+      e.listQuote(it)
+    }
+  }
+}
+
+func (e *extraction) listQuote(it *item) {
+  quote, attribution := splitAttribution(trimBlanks(it.nodes))
+  parts := []string{plainText(quote)}
+  sources := make([]*item, 0)
+  if attribution != nil {
+    sources = append(sources, &item{nodes: attribution, span: it.span})
+  }
+  for _, c := range it.children {
+    switch c.prefix {
+    case "**":
+      sources = append(sources, c)
+    case "*:":
+      parts = append(parts, plainText(c.nodes))
+    }
+  }
+  text := strings.Join(parts, "\n")
+
+  if len(sources) == 0 {
+    e.reject(ReasonNoSource, it.span, text, "the quote has no source item")
+    return
+  }
+  var reason Reason
+  var message string
+  for ix, s := range sources {
+    q, r, m := e.source(s.nodes)
+    if r == "" {
+      q.Quote, q.Span = text, it.span
+      e.add(q)
+      return
+    }
+    if ix == 0 {
+      reason, message = r, m
+    }
+  }
+  e.reject(reason, sources[0].span, text, "%s", message)
+}
+
+// splitAttribution cuts a "quote ~ [[Author]], source" item at the ~
+func splitAttribution(ns Nodes) (Nodes, Nodes) {
+  for ix := len(ns) - 1; ix >= 0; ix-- {
+    if ns[ix].Typ != NodeText || strings.TrimSpace(ns[ix].Val) != "~" {
+      continue
+    }
+    if rest := trimBlanks(ns[ix+1:]); len(rest) > 0 && rest[0].Typ == NodeLink {
+      return ns[:ix], rest
+    }
+  }
+  return ns, nil
+}
+
+// trimBlanks drops the leading blank text nodes, and the stray list markup
+// of the "** ** [[Author]]" items
+func trimBlanks(ns Nodes) Nodes {
+  for len(ns) > 0 && ns[0].Typ == NodeText && strings.Trim(ns[0].Val, "* \t") == "" {
+    ns = ns[1:]
+  }
+  return ns
+}
+
+// source reads the author and the source of an attribution, "[[Author]], source",
+// "Author, source" or a source template. It returns the reason when there is
+// no author.
+func (e *extraction) source(ns Nodes) (Quote, Reason, string) {
+  ns = trimBlanks(ns)
+  if len(ns) > 0 && ns[0].Typ == NodeText && strings.TrimSpace(ns[0].Val) == "~" {
+    ns = trimBlanks(ns[1:])
+  }
+
+  n, t, found := e.sourceTemplate(ns)
+  var q Quote
+  if found {
+    q = Quote{Author: e.profile.templateParam(n, t, "author"), Source: e.profile.templateSource(n, t)}
+  }
+
+  if len(ns) > 0 && ns[0].Typ == NodeLink {
+    q.Author = plainText(ns[0:1])
+    if !found {
+      q.Source = e.profile.textSource(strings.TrimSpace(strings.TrimLeft(plainText(ns[1:]), ",;:-–— ")))
+    }
+    return q, "", ""
+  }
+  if !found {
+    author, source, ok := splitAuthor(ns)
+    if !ok {
+      return q, ReasonUnsupportedSource, "the source starts with neither an author nor a source template: " + strings.TrimSpace(ns.StringRepresentation())
+    }
+    q.Author, q.Source = author, e.profile.textSource(source)
+    return q, "", ""
+  }
+  if q.Author == "" {
+    return q, ReasonNoAuthor, "{{" + t.Name + "}} has no author"
+  }
+  return q, "", ""
+}
+
+// sourceTemplate returns the first source template of the nodes, with its profile template
+func (e *extraction) sourceTemplate(ns Nodes) (Node, *Template, bool) {
+  for _, n := range ns {
+    if n.Typ != NodeTemplate {
+      continue
+    }
+    if t, ok := e.profile.sourceTemplate(n); ok {
+      return n, t, true
+    }
+  }
+  return Node{}, nil, false
+}
+
+// maxAuthorWords bounds the words of an author given as text, such as
+// "Commander William Adama (played by Edward James Olmos)"
+const maxAuthorWords = 8
+
+// splitAuthor cuts an "Author, source" attribution at its first comma. The
+// text before the comma is no author when it holds markup, as the emphasized
+// or quoted works do, or when it reads as a sentence: the notes and the quotes
+// mistaken for a source.
+func splitAuthor(ns Nodes) (string, string, bool) {
+  for ix, n := range ns {
+    comma := strings.Index(n.Val, ",")
+    if n.Typ != NodeText || comma < 0 {
+      continue
+    }
+    author := append(append(Nodes{}, ns[:ix]...), Node{Typ: NodeText, Val: n.Val[:comma]})
+    source := append(Nodes{{Typ: NodeText, Val: n.Val[comma+1:]}}, ns[ix+1:]...)
+    raw := author.StringRepresentation()
+    if strings.Contains(raw, "''") || strings.ContainsAny(raw, "\"<:;") || len(strings.Fields(raw)) > maxAuthorWords {
+      return "", "", false
+    }
+    // the [ of a broken [[Author]] link is text
+    name := strings.TrimSpace(strings.TrimLeft(plainText(author), "["))
+    return name, plainText(source), name != ""
+  }
+  return "", "", false
+}
+
+// textRenderer renders the text of the list items, without their emphasis
+var textRenderer = newTextRenderer()
+
+func newTextRenderer() *TextRenderer {
+  r := NewTextRenderer()
+  r.CollapseWhitespace = true
+  r.StripEmphasis = true
+  return r
+}
+
+// plainText renders nodes of a list item as plain text, without the markup of
+// the poems
+func plainText(ns Nodes) string {
+  return strings.TrimSpace(poemTag.ReplaceAllString(textRenderer.Render(ns), ""))
+}
@@ -0,0 +1,214 @@
+package quotes
+
+import (
+  . "github.com/octplane/wikiquote-parser"
+  "strings"
+  "testing"
+)
+
+const amour = `{{en cours.CM}}
+{{citation|citation=Aimer, c’est trouver sa richesse hors de soi.}}
+{{Réf Livre|titre=Éléments de philosophie (Gallimard)|auteur=Alain |éditeur=Librairie Larousse|année=1980 |page=3|isbn=2-03-340809-4}}
+
+{{début cadre}}
+{{Citation|Il n'y a qu'un bonheur dans la vie, c'est d'aimer et d'être aimé.}}
+{{Réf Livre|titre=Lettre à Maurice Sand|auteur=George Sand|ISBN=978-2-07-038456-1}}
+{{fin cadre}}
+
+{{citation|Une citation orpheline.}}
+{{citation| }}
+{{Réf Pub|auteur=Anonyme}}
+
+[[Catégorie:Amour]]
+[[Catégorie:Sentiment]]
+`
+
+func TestExtractTemplates(t *testing.T) {
+  page := Page{ID: 1865, Title: "Amour", Language: "fr"}
+  quotes, rejections := Extract(page, Parse(Tokenize(amour)))
+
+  if len(quotes) != 2 {
+    t.Fatalf("Expected 2 quotes, got %+v", quotes)
+  }
+  q := quotes[0]
+  if q.Quote != "Aimer, c’est trouver sa richesse hors de soi." || strings.TrimSpace(q.Author) != "Alain" || q.Source.Title != "Éléments de philosophie (Gallimard)" || q.Source.Reference != "2-03-340809-4" {
+    t.Errorf("Unexpected quote %+v", q)
+  }
+  if q.Language != "fr" || q.PageID != 1865 || q.PageTitle != "Amour" || q.Source.Template != "Réf Livre" || q.Source.Type != SourceBook {
+    t.Errorf("Unexpected page fields %+v", q)
+  }
+  if strings.Join(q.Categories, ",") != "Amour,Sentiment" {
+    t.Errorf("Unexpected categories %v", q.Categories)
+  }
+  if q.ID != quoteID(q.Quote) || len(q.ID) != 40 {
+    t.Errorf("Unexpected ID %s", q.ID)
+  }
+  if !strings.HasPrefix(amour[q.Span.Start:], "{{citation|citation=Aimer") {
+    t.Errorf("Unexpected span %s", q.Span)
+  }
+
+  // nested in the layout templates
+  if quotes[1].Author != "George Sand" || quotes[1].Source.Reference != "978-2-07-038456-1" {
+    t.Errorf("Unexpected quote %+v", quotes[1])
+  }
+
+  if len(rejections) != 2 {
+    t.Fatalf("Expected 2 rejections, got %+v", rejections)
+  }
+  if rejections[0].Reason != ReasonNoSource || rejections[0].Quote != "Une citation orpheline." {
+    t.Errorf("Unexpected rejection %+v", rejections[0])
+  }
+  if rejections[1].Reason != ReasonEmptyQuote || rejections[1].PageTitle != "Amour" {
+    t.Errorf("Unexpected rejection %+v", rejections[1])
+  }
+}
+
+const love = `== A ==
+* Love flowers best in openness and freedom.
+** [[Edward Abbey]], ''Desert Solitaire'' (1968)
+* '''Mysterious love''', uncertain treasure
+** [[Joseph Addison]], ''Rosamond''
+* To love and win is the best thing,<br/>to love and lose is the next best.
+** William Makepeace Thackeray, ''The History of Pendennis'' (1848-1850)
+* Seize the moments of happiness.
+** ** [[Leo Tolstoy]], ''War and Peace''
+* A quote without source
+
+* A quote with a plain source
+** Anonymous
+
+[[Category:Love]]
+`
+
+func TestExtractList(t *testing.T) {
+  page := Page{ID: 126489, Title: "Love", Language: "en"}
+  quotes, rejections := Extract(page, Parse(Tokenize(love)))
+
+  if len(quotes) != 4 {
+    t.Fatalf("Expected 4 quotes, got %+v", quotes)
+  }
+  q := quotes[0]
+  if q.Quote != "Love flowers best in openness and freedom." || q.Author != "Edward Abbey" || q.Source.Title != "Desert Solitaire (1968)" {
+    t.Errorf("Unexpected quote %+v", q)
+  }
+  if len(q.Categories) != 1 || q.Categories[0] != "Love" {
+    t.Errorf("Unexpected categories %v", q.Categories)
+  }
+  if quotes[1].Quote != "Mysterious love, uncertain treasure" || quotes[1].Author != "Joseph Addison" {
+    t.Errorf("Unexpected quote %+v", quotes[1])
+  }
+  // a plain author is cut at the first comma
+  if quotes[2].Quote != "To love and win is the best thing,\nto love and lose is the next best." || quotes[2].Author != "William Makepeace Thackeray" || quotes[2].Source.Title != "The History of Pendennis (1848-1850)" {
+    t.Errorf("Unexpected quote %+v", quotes[2])
+  }
+  if quotes[3].Author != "Leo Tolstoy" || quotes[3].Source.Title != "War and Peace" {
+    t.Errorf("Unexpected quote %+v", quotes[3])
+  }
+
+  if len(rejections) != 2 || rejections[0].Reason != ReasonNoSource || rejections[1].Reason != ReasonUnsupportedSource {
+    t.Fatalf("Unexpected rejections %+v", rejections)
+  }
+  if rejections[1].Quote != "A quote with a plain source" || love[rejections[1].Span.Start:rejections[1].Span.End] != "** Anonymous" {
+    t.Errorf("Unexpected rejection %+v", rejections[1])
+  }
+}
+
+const sources = `== Quotes ==
+* Love is blind. ~ [[William Shakespeare]], ''The Merchant of Venice''
+* The first part of the quote
+*: and the second one.
+** [[Carl Sagan]], {{cite book
+ |title=Contact
+ |isbn=978-0-671-43400-7
+}}
+* A quote with a template source
+** {{cite web|author=Lao Tzu|title=Tao Te Ching|url=http://example.com/tao}}
+* <poem>Roses are red,
+Violets are blue</poem>
+** ''A translation''
+** ~ [[Anonymous]]
+* A quote without author
+** {{cite book|title=Unknown}}
+
+== See also ==
+* [[Hate]]
+`
+
+func TestExtractListSources(t *testing.T) {
+  quotes, rejections := Extract(Page{Title: "Love", Language: "en"}, Parse(Tokenize(sources)))
+
+  expected := [][]string{
+    {"Love is blind.", "William Shakespeare", "The Merchant of Venice", "", "", ""},
+    {"The first part of the quote\nand the second one.", "Carl Sagan", "Contact", "cite book", "book", "978-0-671-43400-7"},
+    {"A quote with a template source", "Lao Tzu", "Tao Te Ching", "cite web", "web", "http://example.com/tao"},
+    {"Roses are red,\nViolets are blue", "Anonymous", "", "", "", ""},
+  }
+  if len(quotes) != len(expected) {
+    t.Fatalf("Expected %d quotes, got %+v", len(expected), quotes)
+  }
+  for ix, q := range quotes {
+    provided := []string{q.Quote, q.Author, q.Source.Title, q.Source.Template, string(q.Source.Type), q.Record()[6]}
+    if strings.Join(provided, "|") != strings.Join(expected[ix], "|") {
+      t.Errorf("Expected %q, got %q", expected[ix], provided)
+    }
+  }
+  if !strings.HasPrefix(sources[quotes[1].Span.Start:], "* The first part") {
+    t.Errorf("Unexpected span %s", quotes[1].Span)
+  }
+
+  // the See also section is skipped
+  if len(rejections) != 1 || rejections[0].Reason != ReasonNoAuthor || rejections[0].Quote != "A quote without author" {
+    t.Errorf("Unexpected rejections %+v", rejections)
+  }
+}
+
+func TestExtractUnsupportedLanguage(t *testing.T) {
+  quotes, rejections := Extract(Page{Title: "Amor", Language: "es"}, Parse(Tokenize(amour)))
+  if len(quotes) != 0 || len(rejections) != 1 || rejections[0].Reason != ReasonUnsupportedLanguage {
+    t.Errorf("Unexpected extraction %+v %+v", quotes, rejections)
+  }
+}
+
+func TestValidate(t *testing.T) {
+  quotes := []Quote{
+    {Quote: "Aimer, c’est trouver sa richesse hors de soi.", Author: "Alain"},
+    {Quote: "Sans auteur", Author: " "},
+    {Quote: strings.Repeat("a", 100), Author: "Alain"},
+  }
+  valid, rejections := Validate(quotes, 100)
+  if len(valid) != 1 || len(rejections) != 2 || rejections[0].Reason != ReasonNoAuthor || rejections[1].Reason != ReasonTooLong {
+    t.Errorf("Unexpected validation %+v %+v", valid, rejections)
+  }
+  if valid, _ := Validate(quotes, 0); len(valid) != 2 {
+    t.Errorf("Expected no length limit, got %+v", valid)
+  }
+}
+
+func TestRecord(t *testing.T) {
+  q := Quote{ID: "abc", PageID: 1865, PageTitle: "Amour", Quote: "Aimer", Author: "Alain", Speaker: "Alceste", Source: Source{Type: SourceBook, Title: "Éléments", Reference: "2-03", URL: "http://example.com"}}
+  if strings.Join(q.Record(), ",") != "abc,1865,Amour,Aimer,Alain,Éléments,2-03,book,Alceste," {
+    t.Errorf("Unexpected record %v", q.Record())
+  }
+  q.Source.Reference = ""
+  if q.Record()[6] != "http://example.com" {
+    t.Errorf("Expected the URL as reference, got %v", q.Record())
+  }
+}
+
+func TestSplitAuthor(t *testing.T) {
+  cases := []struct{ source, author, title string }{
+    {"Sir Walter Raleigh, ''Love the Only Price of Love''.", "Sir Walter Raleigh", "Love the Only Price of Love."},
+    {"[Algernon Charles Swinburne]], ''Love at Sea''.", "Algernon Charles Swinburne", "Love at Sea."},
+    {"Song of Solomon VIII. 7.", "", ""},
+    {"''Maud; A Monodrama'' (1855), Part XXII", "", ""},
+    {`"Sheik", ''The Legend of Zelda''.`, "", ""},
+    {"Context: Cool off; don't let her think you too importunate, Ovid", "", ""},
+    {"One seeks to make the loved one entirely happy, or, if that cannot be", "", ""},
+  }
+  for _, c := range cases {
+    author, title, ok := splitAuthor(Parse(Tokenize(c.source)))
+    if ok != (c.author != "") || author != c.author || title != c.title {
+      t.Errorf("Expected %q, %q from %q, got %q, %q", c.author, c.title, c.source, author, title)
+    }
+  }
+}
@@ -0,0 +1,137 @@
+package quotes
+
+import (
+  . "github.com/octplane/wikiquote-parser"
+  "strings"
+)
+
+// line is a line of the top level nodes, without its line feed
+type line struct {
+  nodes Nodes
+  span  Span
+}
+
+// lines splits the top level nodes on their line feeds. Headings are lines
+// of their own.
+func lines(nodes Nodes) []line {
+  ret := make([]line, 0)
+  current := make(Nodes, 0)
+  flush := func() {
+    l := line{nodes: current}
+    if len(current) > 0 {
+      l.span = Span{Start: current[0].Span.Start, End: current[len(current)-1].Span.End}
+    }
+    ret = append(ret, l)
+    current = make(Nodes, 0)
+  }
+  for _, n := range nodes {
+    switch {
+    case n.Typ == NodeText && n.Val == "\n":
+      flush()
+    case n.Typ == NodeTitle:
+      flush()
+      current = append(current, n)
+      flush()
+    default:
+      current = append(current, n)
+    }
+  }
+  if len(current) > 0 {
+    flush()
+  }
+  return ret
+}
+
+// item is a list item of a page, with the items nested under it
+//    - prefix is the list markup of the item: *, **, *:...
+//    - nodes are the contents of the item, without the prefix
+//    - section is the heading the item is under
+type item struct {
+  prefix   string
+  nodes    Nodes
+  span     Span
+  section  string
+  children []*item
+}
+
+// items builds the list items of the top level nodes. An item holding an
+// unclosed <poem> goes on until the poem is closed, any other line ends the
+// lists.
+func items(nodes Nodes) []*item {
+  ret := make([]*item, 0)
+  open := make([]*item, 0)
+  section := ""
+
+  ls := lines(nodes)
+  for ix := 0; ix < len(ls); ix++ {
+    l := ls[ix]
+    if len(l.nodes) == 1 && l.nodes[0].Typ == NodeTitle {
+      section = strings.TrimSpace(l.nodes[0].NamedParams["title"].StringRepresentation())
+      open = open[:0]
+      continue
+    }
+    prefix, ns := listPrefix(l.nodes)
+    if prefix == "" {
+      open = open[:0]
+      continue
+    }
+    it := &item{prefix: prefix, nodes: ns, span: l.span, section: section}
+    for inPoem(it.nodes) && ix+1 < len(ls) {
+      ix += 1
+      it.nodes = append(append(it.nodes, Node{Typ: NodeText, Val: "\n"}), ls[ix].nodes...)
+      it.span.End = ls[ix].span.End
+    }
+
+    for len(open) > 0 && !nested(open[len(open)-1].prefix, prefix) {
+      open = open[:len(open)-1]
+    }
+    if len(open) == 0 {
+      ret = append(ret, it)
+    } else {
+      parent := open[len(open)-1]
+      parent.children = append(parent.children, it)
+    }
+    open = append(open, it)
+  }
+  return ret
+}
+
+// nested tells whether a prefix is the one of an item nested in the parent prefix
+func nested(parent string, prefix string) bool {
+  return len(parent) < len(prefix) && strings.HasPrefix(prefix, parent)
+}
+
+// listPrefix cuts the list markup at the start of a line, and the blanks following it
+func listPrefix(ns Nodes) (string, Nodes) {
+  if len(ns) == 0 || ns[0].Typ != NodeText {
+    return "", ns
+  }
+  val := ns[0].Val
+  end := 0
+  for end < len(val) && strings.IndexByte("*#:;", val[end]) >= 0 {
+    end += 1
+  }
+  if end == 0 {
+    return "", ns
+  }
+
+  rest := append(Nodes{}, ns...)
+  rest[0].Val = val[end:]
+  rest[0].Span.Start += end
+  for len(rest) > 0 && rest[0].Typ == NodeText {
+    trimmed := strings.TrimLeft(rest[0].Val, " \t")
+    if trimmed != "" {
+      rest[0].Span.Start += len(rest[0].Val) - len(trimmed)
+      rest[0].Val = trimmed
+      break
+    }
+    rest = rest[1:]
+  }
+  return val[:end], rest
+}
+
+// inPoem tells whether a <poem> of the nodes is left open
+func inPoem(ns Nodes) bool {
+  text := strings.ToLower(ns.StringRepresentation())
+  return strings.LastIndex(text, "<poem") > strings.LastIndex(text, "</poem")
+}
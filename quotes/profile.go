@@ -0,0 +1,222 @@
+package quotes
+
+import (
+  "encoding/json"
+  "fmt"
+  . "github.com/octplane/wikiquote-parser"
+  "io"
+  "strings"
+)
+
+// Strategies of the profiles
+//    - StrategyTemplates pairs the quote templates with the source template next to them
+//    - StrategyList reads the "* quote" list items followed by a "** [[Author]], source" item
+const (
+  StrategyTemplates = "templates"
+  StrategyList      = "list"
+)
+
+// Template names a template and its other names. Type and Params are the
+// ones of the source templates, Params replacing the profile ones.
+type Template struct {
+  Name    string              `json:"name"`
+  Aliases []string            `json:"aliases,omitempty"`
+  Type    SourceType          `json:"type,omitempty"`
+  Params  map[string][]string `json:"params,omitempty"`
+}
+
+// Profile holds what differs from a wikiquote to another, see profiles/*.json
+//    - Strategy is how the quotes are laid out on the pages, StrategyTemplates or StrategyList
+//    - Params are the template parameters of the quote, author and Source fields, the first present wins
+//    - FeaturedTemplates date the quote they follow as quote of the day, with the day, month and year Params
+//    - SpeakerTemplates start the lines of the dialogues in a quote, their first parameter being the speaker
+//    - SourceRules classify the sources given as text, the first matching rule wins
+//    - CategoryNamespace are the names of the category namespace, local and canonical
+//    - SkipSections are the headings of the sections holding no quotes, like "See also"
+//    - Output is the prefix of the files written by the commands
+type Profile struct {
+  Language          string              `json:"language"`
+  Strategy          string              `json:"strategy"`
+  QuoteTemplates    []Template          `json:"quote_templates,omitempty"`
+  SourceTemplates   []Template          `json:"source_templates,omitempty"`
+  SpeakerTemplates  []Template          `json:"speaker_templates,omitempty"`
+  FeaturedTemplates []Template          `json:"featured_templates,omitempty"`
+  Params            map[string][]string `json:"params,omitempty"`
+  SourceRules       []SourceRule        `json:"source_rules,omitempty"`
+  CategoryNamespace []string            `json:"category_namespace"`
+  SkipSections      []string            `json:"skip_sections,omitempty"`
+  Output            string              `json:"output"`
+
+  // canonical template names to their Template
+  quotes   map[string]*Template
+  sources  map[string]*Template
+  speakers map[string]*Template
+  featured map[string]*Template
+}
+
+// LoadProfile reads a JSON profile
+func LoadProfile(r io.Reader) (*Profile, error) {
+  p := &Profile{}
+  if err := json.NewDecoder(r).Decode(p); err != nil {
+    return nil, err
+  }
+  if err := p.compile(); err != nil {
+    return nil, err
+  }
+  return p, nil
+}
+
+func (p *Profile) compile() error {
+  if p.Language == "" {
+    return fmt.Errorf("profile without language")
+  }
+  if p.Strategy != StrategyTemplates && p.Strategy != StrategyList {
+    return fmt.Errorf("profile %s: unknown strategy %q", p.Language, p.Strategy)
+  }
+  if p.Output == "" {
+    p.Output = "quotes-" + p.Language
+  }
+  for _, t := range p.SourceTemplates {
+    if !t.Type.valid() {
+      return fmt.Errorf("profile %s, template %s: unknown source type %q", p.Language, t.Name, t.Type)
+    }
+  }
+  for ix := range p.SourceRules {
+    if err := p.SourceRules[ix].compile(); err != nil {
+      return fmt.Errorf("profile %s, source rule %d: %s", p.Language, ix, err)
+    }
+  }
+  p.quotes = templateNames(p.QuoteTemplates)
+  p.sources = templateNames(p.SourceTemplates)
+  p.speakers = templateNames(p.SpeakerTemplates)
+  p.featured = templateNames(p.FeaturedTemplates)
+  return nil
+}
+
+func templateNames(ts []Template) map[string]*Template {
+  ret := make(map[string]*Template)
+  for ix, t := range ts {
+    for _, name := range append([]string{t.Name}, t.Aliases...) {
+      ret[CanonicalTemplateName(name)] = &ts[ix]
+    }
+  }
+  return ret
+}
+
+// quoteTemplate returns the profile template of a quote template call
+func (p *Profile) quoteTemplate(n Node) (*Template, bool) {
+  t, ok := p.quotes[CanonicalTemplateName(n.StringParamOrEmpty("name"))]
+  return t, ok
+}
+
+// sourceTemplate returns the profile template of a source template call
+func (p *Profile) sourceTemplate(n Node) (*Template, bool) {
+  t, ok := p.sources[CanonicalTemplateName(n.StringParamOrEmpty("name"))]
+  return t, ok
+}
+
+// speakerTemplate tells whether a template call names the speaker of a line
+func (p *Profile) speakerTemplate(n Node) bool {
+  _, ok := p.speakers[CanonicalTemplateName(n.StringParamOrEmpty("name"))]
+  return ok
+}
+
+// param is the trimmed value of a quote field in a template, empty when missing
+func (p *Profile) param(n Node, field string) string {
+  v, ok := FirstParam(n, p.Params[field]...)
+  if !ok {
+    return ""
+  }
+  return strings.TrimSpace(v.StringRepresentation())
+}
+
+// category returns the category a link points to
+func (p *Profile) category(n Node) (string, bool) {
+  link := n.StringParamOrEmpty("link")
+  for _, ns := range p.CategoryNamespace {
+    if strings.HasPrefix(link, ns+":") {
+      return strings.TrimPrefix(link, ns+":"), true
+    }
+  }
+  return "", false
+}
+
+// Profiles are the profiles by language used by Extract, fr and en are built in
+var Profiles = map[string]*Profile{
+  "fr": mustCompile(&Profile{
+    Language:       "fr",
+    Strategy:       StrategyTemplates,
+    QuoteTemplates: []Template{{Name: "citation"}},
+    SourceTemplates: []Template{
+      {Name: "Réf Livre", Aliases: []string{"Réf livre"}, Type: SourceBook},
+      {Name: "Réf Article", Type: SourceArticle},
+      // publications, the name being the title
+      {Name: "Réf Pub", Type: SourceArticle, Params: map[string][]string{"title": {"nom"}, "publication": {"parution", "source"}}},
+      {Name: "Réf Film", Type: SourceFilm},
+      {Name: "Réf Chanson", Type: SourceSong, Params: map[string][]string{"publication": {"album"}}},
+    },
+    SpeakerTemplates:  []Template{{Name: "Personnage"}},
+    FeaturedTemplates: []Template{{Name: "Choisie citation du jour"}},
+    Params: map[string][]string{
+      "quote":       {"#0", "1", "citation"},
+      "author":      {"auteur", "author"},
+      "title":       {"titre", "title"},
+      "publication": {"publication"},
+      "director":    {"réalisateur"},
+      "season":      {"saison"},
+      "episode":     {"épisode"},
+      "date":        {"date", "année"},
+      "url":         {"url"},
+      "reference":   {"ISBN", "isbn"},
+      "day":         {"jour"},
+      "month":       {"mois"},
+      "year":        {"année"},
+    },
+    CategoryNamespace: []string{"Catégorie", "Category"},
+    Output:            "quotes-fr",
+  }),
+  "en": mustCompile(&Profile{
+    Language: "en",
+    Strategy: StrategyList,
+    SourceTemplates: []Template{
+      {Name: "cite book", Type: SourceBook},
+      {Name: "cite journal", Type: SourceArticle},
+      {Name: "cite magazine", Type: SourceArticle},
+      {Name: "cite news", Type: SourceArticle},
+      {Name: "cite web", Type: SourceWeb, Params: map[string][]string{"publication": {"website", "work", "publisher"}}},
+      {Name: "cite speech", Type: SourceSpeech, Params: map[string][]string{"publication": {"event"}}},
+      {Name: "cite episode", Type: SourceTV, Params: map[string][]string{"publication": {"series"}, "director": {"director"}}},
+      {Name: "cite AV media", Type: SourceFilm, Params: map[string][]string{"author": {"people", "author", "last"}, "director": {"director"}}},
+    },
+    Params: map[string][]string{
+      "author":      {"author", "last", "author1", "last1"},
+      "title":       {"title"},
+      "publication": {"journal", "magazine", "newspaper", "work", "periodical"},
+      "season":      {"season", "series-no", "seriesno"},
+      "episode":     {"number", "episode"},
+      "date":        {"date", "year"},
+      "url":         {"url"},
+      "reference":   {"isbn", "ISBN", "doi"},
+    },
+    SourceRules: []SourceRule{
+      {Type: SourceTV, Pattern: `(?i)\b(tv|television|sitcom|season \d+|episode)\b`},
+      {Type: SourceFilm, Pattern: `(?i)\bfilm\b|\(\d{4} movie\)`},
+      {Type: SourceSong, Pattern: `(?i)\b(song|lyrics|album|single)\b`},
+      {Type: SourceSpeech, Pattern: `(?i)\b(speech|address|lecture|sermon|remarks)\b`},
+      {Type: SourceAdvertisement, Pattern: `(?i)\b(advertis\w*|slogan|commercial)\b`},
+      {Type: SourceArticle, Pattern: `(?i)\b(interview|magazine|journal|newspaper|review)\b`},
+      {Type: SourceWeb, Pattern: `(?i)\b(blog|website|twitter|tweet)\b`},
+      {Type: SourceBook, Pattern: `(?i)\b(chapter|volume|book|novel|poem|essay)\b|\b(ch|vol|pp?)\.`},
+    },
+    CategoryNamespace: []string{"Category"},
+    SkipSections:      []string{"See also", "External links", "Disputed", "Misattributed"},
+    Output:            "quotes-en",
+  }),
+}
+
+func mustCompile(p *Profile) *Profile {
+  if err := p.compile(); err != nil {
+    panic(err)
+  }
+  return p
+}
@@ -0,0 +1,69 @@
+package quotes
+
+import (
+  . "github.com/octplane/wikiquote-parser"
+  "os"
+  "reflect"
+  "strings"
+  "testing"
+)
+
+func TestProfileFiles(t *testing.T) {
+  for lang, fname := range map[string]string{"fr": "../profiles/frwikiquote.json", "en": "../profiles/enwikiquote.json"} {
+    fi, err := os.Open(fname)
+    if err != nil {
+      t.Fatal(err)
+    }
+    p, err := LoadProfile(fi)
+    fi.Close()
+    if err != nil {
+      t.Fatalf("%s: %s", fname, err)
+    }
+    if !reflect.DeepEqual(p, Profiles[lang]) {
+      t.Errorf("%s differs from the built in profile:\n%+v\n%+v", fname, p, Profiles[lang])
+    }
+  }
+}
+
+const spanish = `{
+  "language": "es",
+  "strategy": "templates",
+  "quote_templates": [{"name": "Cita", "aliases": ["cita requerida"]}],
+  "source_templates": [{"name": "Cita libro", "aliases": ["Ref libro"]}],
+  "params": {
+    "quote": ["#0", "texto"],
+    "author": ["autor"],
+    "title": ["título"],
+    "reference": ["isbn"]
+  },
+  "category_namespace": ["Categoría", "Category"]
+}`
+
+func TestLoadProfile(t *testing.T) {
+  p, err := LoadProfile(strings.NewReader(spanish))
+  if err != nil {
+    t.Fatal(err)
+  }
+  if p.Output != "quotes-es" {
+    t.Errorf("Expected the default output, got %s", p.Output)
+  }
+
+  text := "{{cita|texto=El amor es ciego.}}\n{{ref_libro|autor=Anónimo|título=Refranero}}\n[[Categoría:Amor]]"
+  quotes, rejections := p.Extract(Page{ID: 7, Title: "Amor"}, Parse(Tokenize(text)))
+  if len(quotes) != 1 || len(rejections) != 0 {
+    t.Fatalf("Unexpected extraction %+v %+v", quotes, rejections)
+  }
+  q := quotes[0]
+  if q.Language != "es" || q.Quote != "El amor es ciego." || q.Author != "Anónimo" || q.Source.Title != "Refranero" || q.Source.Template != "Cita libro" {
+    t.Errorf("Unexpected quote %+v", q)
+  }
+  if len(q.Categories) != 1 || q.Categories[0] != "Amor" {
+    t.Errorf("Unexpected categories %v", q.Categories)
+  }
+
+  for _, bad := range []string{`{"strategy": "list"}`, `{"language": "de", "strategy": "tables"}`, `{"language": `} {
+    if _, err := LoadProfile(strings.NewReader(bad)); err == nil {
+      t.Errorf("Expected an error for %s", bad)
+    }
+  }
+}
@@ -0,0 +1,121 @@
+// Package quotes extracts the quotes of wikiquote pages from their parsed
+// nodes, in the pivot format described by PIVOTFORMAT.md.
+package quotes
+
+import (
+  "crypto/sha1"
+  "fmt"
+  . "github.com/octplane/wikiquote-parser"
+  "io"
+  "strconv"
+  "strings"
+  "time"
+)
+
+// Page is the wikiquote page the quotes are extracted from. Language is the
+// code of the wikiquote, the key of its profile in Profiles.
+type Page struct {
+  ID       int
+  Title    string
+  Language string
+}
+
+// ImportJob is a run of an importer over a dump
+type ImportJob struct {
+  ID       string    `json:"id"`
+  Start    time.Time `json:"start"`
+  End      time.Time `json:"end"`
+  Importer string    `json:"importer"`
+}
+
+// Quote is a quote in the pivot format
+//    - ID is the sha1 of the quote text
+//    - Speaker is the character saying the quote in a film or a play, Dialogue the turns of an exchange
+//    - Source is the work the quote comes from, with its type and absolute reference
+//    - Featured are the days the quote was the quote of the day
+//    - ImportJob is the ID of the ImportJob, left to the importer
+type Quote struct {
+  ID         string      `json:"id"`
+  Language   string      `json:"language"`
+  PageID     int         `json:"page_id"`
+  PageTitle  string      `json:"page_title"`
+  Categories []string    `json:"categories"`
+  Author     string      `json:"author"`
+  Quote      string      `json:"quote"`
+  Speaker    string      `json:"speaker,omitempty"`
+  Dialogue   []Turn      `json:"dialogue,omitempty"`
+  Source     Source      `json:"source"`
+  Featured   []time.Time `json:"featured,omitempty"`
+  ImportJob  string      `json:"import_job,omitempty"`
+  Span       Span        `json:"span"`
+}
+
+// Record is the CSV record of the quote written by the extraction commands:
+// id, page id, page title, quote, author, source title, source reference (its
+// URL when it has none), source type, speaker and featured days
+func (q Quote) Record() []string {
+  ref := q.Source.Reference
+  if ref == "" {
+    ref = q.Source.URL
+  }
+  days := make([]string, 0, len(q.Featured))
+  for _, d := range q.Featured {
+    days = append(days, d.Format(dayLayout))
+  }
+  return []string{q.ID, strconv.Itoa(q.PageID), q.PageTitle, q.Quote, q.Author, q.Source.Title, ref, string(q.Source.Type), q.Speaker, strings.Join(days, " ")}
+}
+
+// quoteID hashes the text of a quote
+func quoteID(text string) string {
+  h := sha1.New()
+  io.WriteString(h, text)
+  return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// Reason tells why a candidate quote was rejected
+type Reason string
+
+const (
+  ReasonEmptyQuote          = Reason("empty-quote")
+  ReasonNoSource            = Reason("no-source")
+  ReasonUnsupportedSource   = Reason("unsupported-source")
+  ReasonNoAuthor            = Reason("no-author")
+  ReasonTooLong             = Reason("too-long")
+  ReasonUnsupportedLanguage = Reason("unsupported-language")
+  ReasonInvalidFeatured     = Reason("invalid-featured")
+)
+
+// Rejection is a candidate quote that was not extracted
+type Rejection struct {
+  PageID    int    `json:"page_id"`
+  PageTitle string `json:"page_title"`
+  Reason    Reason `json:"reason"`
+  Message   string `json:"message"`
+  Quote     string `json:"quote,omitempty"`
+  Span      Span   `json:"span"`
+}
+
+func (r Rejection) String() string {
+  return fmt.Sprintf("%s:%s: %s [%s]", r.PageTitle, r.Span, r.Message, r.Reason)
+}
+
+// Validate drops the quotes without author and the ones having maxLength
+// bytes or more, 0 for no limit
+func Validate(quotes []Quote, maxLength int) ([]Quote, []Rejection) {
+  valid := make([]Quote, 0, len(quotes))
+  rejections := make([]Rejection, 0)
+  for _, q := range quotes {
+    r := Rejection{PageID: q.PageID, PageTitle: q.PageTitle, Quote: q.Quote, Span: q.Span}
+    switch {
+    case strings.TrimSpace(q.Author) == "":
+      r.Reason, r.Message = ReasonNoAuthor, "the source has no author"
+    case maxLength > 0 && len(q.Quote) >= maxLength:
+      r.Reason, r.Message = ReasonTooLong, fmt.Sprintf("the quote has length %d", len(q.Quote))
+    default:
+      valid = append(valid, q)
+      continue
+    }
+    rejections = append(rejections, r)
+  }
+  return valid, rejections
+}
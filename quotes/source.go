@@ -0,0 +1,120 @@
+package quotes
+
+import (
+  "fmt"
+  . "github.com/octplane/wikiquote-parser"
+  "regexp"
+  "strings"
+)
+
+// SourceType is the kind of work a quote comes from, empty when unknown
+type SourceType string
+
+const (
+  SourceBook          = SourceType("book")
+  SourceArticle       = SourceType("article")
+  SourceFilm          = SourceType("film")
+  SourceTV            = SourceType("tv")
+  SourceSpeech        = SourceType("speech")
+  SourceSong          = SourceType("song")
+  SourceAdvertisement = SourceType("advertisement")
+  SourceWeb           = SourceType("web")
+)
+
+var sourceTypes = []SourceType{SourceBook, SourceArticle, SourceFilm, SourceTV, SourceSpeech, SourceSong, SourceAdvertisement, SourceWeb}
+
+func (t SourceType) valid() bool {
+  if t == "" {
+    return true
+  }
+  for _, st := range sourceTypes {
+    if t == st {
+      return true
+    }
+  }
+  return false
+}
+
+// Source is the work a quote comes from, the fields depending on its type
+//    - Template is the profile name of the source template, empty for a text source
+//    - Publication is the journal of an article, the series of an episode or the album of a song
+//    - Director, Season and Episode are the ones of films and TV series
+//    - Reference is an absolute reference of the work, like an ISBN or a DOI
+type Source struct {
+  Type        SourceType `json:"type,omitempty"`
+  Template    string     `json:"template,omitempty"`
+  Title       string     `json:"title,omitempty"`
+  Publication string     `json:"publication,omitempty"`
+  Director    string     `json:"director,omitempty"`
+  Season      string     `json:"season,omitempty"`
+  Episode     string     `json:"episode,omitempty"`
+  Date        string     `json:"date,omitempty"`
+  URL         string     `json:"url,omitempty"`
+  Reference   string     `json:"reference,omitempty"`
+}
+
+// SourceRule classifies the text sources matching Pattern as Type
+type SourceRule struct {
+  Type    SourceType `json:"type"`
+  Pattern string     `json:"pattern"`
+  re      *regexp.Regexp
+}
+
+func (r *SourceRule) compile() error {
+  if !r.Type.valid() {
+    return fmt.Errorf("unknown source type %q", r.Type)
+  }
+  re, err := regexp.Compile(r.Pattern)
+  if err != nil {
+    return err
+  }
+  r.re = re
+  return nil
+}
+
+// templateSource reads the fields of a source template
+func (p *Profile) templateSource(n Node, t *Template) Source {
+  s := Source{
+    Type:        t.Type,
+    Template:    t.Name,
+    Title:       p.templateParam(n, t, "title"),
+    Publication: p.templateParam(n, t, "publication"),
+    Director:    p.templateParam(n, t, "director"),
+    Season:      p.templateParam(n, t, "season"),
+    Episode:     p.templateParam(n, t, "episode"),
+    Date:        p.templateParam(n, t, "date"),
+    URL:         p.templateParam(n, t, "url"),
+    Reference:   p.templateParam(n, t, "reference"),
+  }
+  // the same templates quote films and series
+  if s.Type == SourceFilm && (s.Season != "" || s.Episode != "") {
+    s.Type = SourceTV
+  }
+  return s
+}
+
+// textSource is a source given as text, classified by the profile rules
+func (p *Profile) textSource(text string) Source {
+  s := Source{Title: text}
+  for _, r := range p.SourceRules {
+    if r.re.MatchString(text) {
+      s.Type = r.Type
+      break
+    }
+  }
+  return s
+}
+
+// templateParam is the trimmed value of a field in a source template, the
+// parameters of the template replacing the ones of the profile
+func (p *Profile) templateParam(n Node, t *Template, field string) string {
+  names, ok := t.Params[field]
+  if !ok {
+    names = p.Params[field]
+  }
+  v, ok := FirstParam(n, names...)
+  if !ok {
+    return ""
+  }
+  return strings.TrimSpace(v.StringRepresentation())
+}
@@ -0,0 +1,78 @@
+package quotes
+
+import (
+  . "github.com/octplane/wikiquote-parser"
+  "strings"
+  "testing"
+)
+
+const films = `{{citation|Je suis ton père.}}
+{{Réf Film|titre=L'Empire contre-attaque|auteur=George Lucas|réalisateur=Irvin Kershner|date=1980}}
+{{citation|D'oh !}}
+{{Réf Film|titre=Les Simpson|auteur=Homer Simpson|saison=2|épisode=3}}
+{{citation|Judas, juda-ah}}
+{{Réf Chanson|titre=Judas|auteur=Lady Gaga|album=Born This Way|date=15 avril 2011}}
+{{citation|Einstein et la conception physique de l’espace}}
+{{Réf Pub|nom=Einstein et l'espace|auteur=Hervé Barreau|parution=Annales de la Fondation Louis de Broglie|date=2005}}
+{{citation|L'homme est un roseau pensant.}}
+{{Réf livre|titre=Pensées|auteur=Blaise Pascal}}`
+
+func TestTemplateSources(t *testing.T) {
+  quotes, rejections := Extract(Page{Title: "Amour", Language: "fr"}, Parse(Tokenize(films)))
+  if len(quotes) != 5 || len(rejections) != 0 {
+    t.Fatalf("Unexpected extraction %+v %+v", quotes, rejections)
+  }
+
+  expected := []Source{
+    {Type: SourceFilm, Template: "Réf Film", Title: "L'Empire contre-attaque", Director: "Irvin Kershner", Date: "1980"},
+    {Type: SourceTV, Template: "Réf Film", Title: "Les Simpson", Season: "2", Episode: "3"},
+    {Type: SourceSong, Template: "Réf Chanson", Title: "Judas", Publication: "Born This Way", Date: "15 avril 2011"},
+    {Type: SourceArticle, Template: "Réf Pub", Title: "Einstein et l'espace", Publication: "Annales de la Fondation Louis de Broglie", Date: "2005"},
+    {Type: SourceBook, Template: "Réf Livre", Title: "Pensées"},
+  }
+  for ix, q := range quotes {
+    if q.Source != expected[ix] {
+      t.Errorf("Expected %+v, got %+v", expected[ix], q.Source)
+    }
+  }
+  if quotes[3].Author != "Hervé Barreau" {
+    t.Errorf("Unexpected author %s", quotes[3].Author)
+  }
+}
+
+const attributions = `* Quote one
+** [[Homer Simpson]], ''The Simpsons'', season 2, episode 3
+* Quote two
+** [[Nike]], advertising slogan (1988)
+* Quote three
+** [[Thomas Browne]], ''Religio Medici'' (1642), Part II, Section IX
+* Quote four
+** [[Martin Luther King]], {{cite speech|title=I Have a Dream|event=March on Washington|date=28 August 1963}}
+* Quote five
+** [[Charles Dickens]], ''A Tale of Two Cities'', Ch. 1
+`
+
+func TestTextSources(t *testing.T) {
+  quotes, _ := Extract(Page{Title: "Love", Language: "en"}, Parse(Tokenize(attributions)))
+  types := make([]string, 0)
+  for _, q := range quotes {
+    types = append(types, string(q.Source.Type))
+  }
+  if strings.Join(types, ",") != "tv,advertisement,,speech,book" {
+    t.Errorf("Unexpected types %v", types)
+  }
+  if s := quotes[3].Source; s.Title != "I Have a Dream" || s.Publication != "March on Washington" || s.Date != "28 August 1963" {
+    t.Errorf("Unexpected source %+v", s)
+  }
+}
+
+func TestSourceTypeErrors(t *testing.T) {
+  for _, bad := range []string{
+    `{"language": "de", "strategy": "list", "source_templates": [{"name": "Zitat", "type": "opera"}]}`,
+    `{"language": "de", "strategy": "list", "source_rules": [{"type": "film", "pattern": "("}]}`,
+  } {
+    if _, err := LoadProfile(strings.NewReader(bad)); err == nil {
+      t.Errorf("Expected an error for %s", bad)
+    }
+  }
+}
@@ -0,0 +1,204 @@
+package wikimediaparser
+
+import (
+  "encoding/json"
+  "fmt"
+  "io"
+  "reflect"
+  "regexp"
+  "strings"
+)
+
+// ParamSchema declares a template parameter
+//    - Name and Aliases are the accepted keys, #0, #1... for positional parameters
+//    - Required parameters must be present and not empty
+//    - Pattern is a regular expression the whole rendered value must match
+type ParamSchema struct {
+  Name     string   `json:"name"`
+  Aliases  []string `json:"aliases,omitempty"`
+  Required bool     `json:"required,omitempty"`
+  Pattern  string   `json:"pattern,omitempty"`
+  re       *regexp.Regexp
+}
+
+// TemplateSchema declares a template, its other names and its parameters
+type TemplateSchema struct {
+  Name         string        `json:"name"`
+  Aliases      []string      `json:"aliases,omitempty"`
+  Params       []ParamSchema `json:"params"`
+  AllowUnknown bool          `json:"allow_unknown,omitempty"`
+}
+
+// SchemaFromStruct builds a template schema from the wq tags of a struct, see Unmarshal
+func SchemaFromStruct(name string, v interface{}) TemplateSchema {
+  t := reflect.TypeOf(v)
+  for t.Kind() == reflect.Ptr {
+    t = t.Elem()
+  }
+  ret := TemplateSchema{Name: name, Params: make([]ParamSchema, 0)}
+  for ix := 0; ix < t.NumField(); ix++ {
+    spec, ok := parseFieldTag(t.Field(ix))
+    if !ok {
+      continue
+    }
+    ret.Params = append(ret.Params, ParamSchema{Name: spec.names[0], Aliases: spec.names[1:], Required: spec.required})
+  }
+  return ret
+}
+
+type violationKind int
+
+const (
+  UnknownParam = violationKind(iota)
+  MissingParam
+  InvalidValue
+)
+
+func (vk violationKind) String() string {
+  switch vk {
+  case UnknownParam:
+    return "unknown parameter"
+  case MissingParam:
+    return "missing parameter"
+  case InvalidValue:
+    return "invalid value"
+  }
+  panic(fmt.Sprintf("Unknown violation kind %d", vk))
+}
+
+// Violation is a template call not matching its schema.
+// Line and Column are only set when the page source is known.
+type Violation struct {
+  Page     string
+  Template string
+  Param    string
+  Kind     violationKind
+  Value    string
+  Span     Span
+  Line     int
+  Column   int
+}
+
+func (v Violation) String() string {
+  msg := fmt.Sprintf("%s: %s %s", v.Template, v.Kind.String(), v.Param)
+  if v.Kind == InvalidValue {
+    msg += fmt.Sprintf(" (%q)", v.Value)
+  }
+  if v.Line > 0 {
+    return fmt.Sprintf("%s:%d:%d: %s", v.Page, v.Line, v.Column, msg)
+  }
+  return fmt.Sprintf("%s:%s: %s", v.Page, v.Span, msg)
+}
+
+// SchemaSet validates template calls against their declared schemas
+type SchemaSet struct {
+  templates map[string]*TemplateSchema
+}
+
+// NewSchemaSet compiles the given schemas
+func NewSchemaSet(schemas ...TemplateSchema) (*SchemaSet, error) {
+  s := &SchemaSet{templates: make(map[string]*TemplateSchema)}
+  for _, t := range schemas {
+    if err := s.Add(t); err != nil {
+      return nil, err
+    }
+  }
+  return s, nil
+}
+
+// LoadSchemas reads a JSON file of the form {"templates": [TemplateSchema...]}
+func LoadSchemas(r io.Reader) (*SchemaSet, error) {
+  var file struct {
+    Templates []TemplateSchema `json:"templates"`
+  }
+  if err := json.NewDecoder(r).Decode(&file); err != nil {
+    return nil, err
+  }
+  return NewSchemaSet(file.Templates...)
+}
+
+// Add compiles and registers a schema under its name and aliases
+func (s *SchemaSet) Add(t TemplateSchema) error {
+  t.Params = append([]ParamSchema{}, t.Params...)
+  for ix, p := range t.Params {
+    if p.Pattern == "" {
+      continue
+    }
+    re, err := regexp.Compile("^(?:" + p.Pattern + ")$")
+    if err != nil {
+      return fmt.Errorf("template %s, parameter %s: %s", t.Name, p.Name, err)
+    }
+    t.Params[ix].re = re
+  }
+  for _, name := range append([]string{t.Name}, t.Aliases...) {
+    s.templates[CanonicalTemplateName(name)] = &t
+  }
+  return nil
+}
+
+// Validate checks every template of the tree, nested ones included
+func (s *SchemaSet) Validate(ns Nodes) []Violation {
+  ret := make([]Violation, 0)
+  Inspect(ns, func(n *Node, parent *Node, path Path) bool {
+    if n == nil || n.Typ != NodeTemplate {
+      return true
+    }
+    name := strings.TrimSpace(n.StringParam("name"))
+    if schema, ok := s.templates[CanonicalTemplateName(name)]; ok {
+      ret = append(ret, schema.validate(name, n)...)
+    }
+    return true
+  })
+  return ret
+}
+
+// ValidatePage parses the page text and validates it, violations carry the page title and line numbers
+func (s *SchemaSet) ValidatePage(title string, text string) []Violation {
+  ret := s.Validate(Parse(Tokenize(text)))
+  for ix := range ret {
+    ret[ix].Page = title
+    ret[ix].Line, ret[ix].Column = LineColumn(text, ret[ix].Span.Start)
+  }
+  return ret
+}
+
+func (t *TemplateSchema) validate(name string, n *Node) []Violation {
+  ret := make([]Violation, 0)
+  violation := func(kind violationKind, param string, value Nodes) {
+    span := value.Span()
+    if span.IsZero() {
+      span = n.Span
+    }
+    ret = append(ret, Violation{
+      Template: name,
+      Param:    param,
+      Kind:     kind,
+      Value:    strings.TrimSpace(value.StringRepresentation()),
+      Span:     span,
+    })
+  }
+
+  used := make(map[string]bool)
+  for _, p := range t.Params {
+    key, value, found := lookupParam(*n, append([]string{p.Name}, p.Aliases...))
+    if found {
+      used[key] = true
+    }
+    text := strings.TrimSpace(value.StringRepresentation())
+    switch {
+    case p.Required && (!found || text == ""):
+      violation(MissingParam, p.Name, nil)
+    case found && text != "" && p.re != nil && !p.re.MatchString(text):
+      violation(InvalidValue, key, value)
+    }
+  }
+
+  if t.AllowUnknown {
+    return ret
+  }
+  for _, k := range unusedParams(n, used) {
+    _, value, _ := lookupParam(*n, []string{k})
+    violation(UnknownParam, k, value)
+  }
+  return ret
+}
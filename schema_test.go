@@ -0,0 +1,63 @@
+package wikimediaparser
+
+import (
+  "os"
+  "testing"
+)
+
+func TestSpans(t *testing.T) {
+  doc := "Texte {{w|Victor Hugo}}\n[[Amour]]"
+  p := Parse(Tokenize(doc))
+
+  assertEqual(t, "Text span", "0-5", p[0].Span.String())
+  assertEqual(t, "Template span", "6-23", p[2].Span.String())
+  assertEqual(t, "Nested span", "10-16", p[2].Params[0][0].Span.String())
+  assertEqual(t, "Link span", "24-33", p[4].Span.String())
+
+  line, col := LineColumn(doc, p[4].Span.Start)
+  assertEqual(t, "Line", 2, line)
+  assertEqual(t, "Column", 1, col)
+}
+
+func TestValidate(t *testing.T) {
+  schemas, err := NewSchemaSet(TemplateSchema{
+    Name: "Réf Livre",
+    Params: []ParamSchema{
+      {Name: "titre", Required: true},
+      {Name: "auteur", Aliases: []string{"author"}, Required: true},
+      {Name: "année", Pattern: "[0-9]{4}"},
+    },
+  })
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  doc := "Début\n{{début cadre|{{réf Livre|titre=Amour|année=vers 1980|éditeur=Larousse}}}}"
+  v := schemas.ValidatePage("Amour", doc)
+  assertEqual(t, "Violation count", 3, len(v))
+  assertEqual(t, "Missing", "Amour:2:15: réf Livre: missing parameter auteur", v[0].String())
+  assertEqual(t, "Invalid", "Amour:2:45: réf Livre: invalid value année (\"vers 1980\")", v[1].String())
+  assertEqual(t, "Unknown", "Amour:2:63: réf Livre: unknown parameter éditeur", v[2].String())
+}
+
+func TestSchemaFromStruct(t *testing.T) {
+  schemas, _ := NewSchemaSet(SchemaFromStruct("Réf Livre", refLivre{}))
+  v := schemas.Validate(Parse(Tokenize("{{Réf Livre|titre=T|author=A|isbn=0}}")))
+  assertEqual(t, "Violation count", 1, len(v))
+  assertEqual(t, "Unknown isbn", "isbn", v[0].Param)
+}
+
+func TestLoadSchemas(t *testing.T) {
+  fi, err := os.Open("schemas/frwikiquote.json")
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer fi.Close()
+
+  schemas, err := LoadSchemas(fi)
+  if err != nil {
+    t.Fatal(err)
+  }
+  doc := "{{citation|Aimer}}\n{{Choisie citation du jour|puce=*|année=2010|mois=mars|jour=1|commentaire=|}}"
+  assertEqual(t, "Valid page", 0, len(schemas.ValidatePage("Amour", doc)))
+}
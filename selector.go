@@ -0,0 +1,484 @@
+package wikimediaparser
+
+import (
+  "fmt"
+  "regexp"
+  "strconv"
+  "strings"
+  "unicode"
+)
+
+// Selectors query a parsed page the way CSS selectors query a document:
+//
+//    template[name~="(?i)citation"]
+//    template[name="Réf Livre"] > param[auteur] link
+//    title[level=4] ~ template
+//
+// A compound starts with a type (template, link, elink, text, title,
+// placeholder, invalid, param or * for any node) followed by attribute
+// filters: [attr], [attr=v], [attr!=v], [attr~=regexp], [attr^=v], [attr$=v]
+// and [attr*=v]. Compounds are chained with combinators: a space for any
+// descendant, > for a direct child and ~ for a following sibling.
+//
+// Parameters are not nodes: param selects the content of a template or link
+// parameter, and param[auteur] is a shorthand for param[name=auteur].
+// The > combinator looks through parameters unless the parent compound is a param.
+//
+// Node attributes are name (template name or link target), link, level,
+// text (the trimmed string representation), val and the named parameters.
+
+type attrOp int
+
+const (
+  opExists = attrOp(iota)
+  opEquals
+  opNotEquals
+  opMatches
+  opPrefix
+  opSuffix
+  opContains
+)
+
+var attrOps = []struct {
+  s  string
+  op attrOp
+}{
+  {"!=", opNotEquals},
+  {"~=", opMatches},
+  {"^=", opPrefix},
+  {"$=", opSuffix},
+  {"*=", opContains},
+  {"=", opEquals},
+}
+
+type attrFilter struct {
+  key   string
+  op    attrOp
+  value string
+  re    *regexp.Regexp
+}
+
+func (f attrFilter) test(v string, ok bool) bool {
+  switch f.op {
+  case opExists:
+    return ok
+  case opNotEquals:
+    return !ok || v != f.value
+  }
+  if !ok {
+    return false
+  }
+  switch f.op {
+  case opEquals:
+    return v == f.value
+  case opMatches:
+    return f.re.MatchString(v)
+  case opPrefix:
+    return strings.HasPrefix(v, f.value)
+  case opSuffix:
+    return strings.HasSuffix(v, f.value)
+  case opContains:
+    return strings.Contains(v, f.value)
+  }
+  return false
+}
+
+type compound struct {
+  typ        string
+  attrs      []attrFilter
+  combinator rune // links this compound to the previous one, 0 for the first compound
+}
+
+var selectorTypes = map[string]nodeType{
+  "template":    NodeTemplate,
+  "link":        NodeLink,
+  "elink":       NodeELink,
+  "text":        NodeText,
+  "title":       NodeTitle,
+  "placeholder": NodePlaceholder,
+  "invalid":     NodeInvalid,
+}
+
+// Selector is a compiled selector expression
+type Selector struct {
+  source string
+  parts  []compound
+}
+
+func (s *Selector) String() string {
+  return s.source
+}
+
+// Match is a selector result
+//    - Path locates the match in the tree
+//    - Node is the matched node, nil when a parameter matched
+//    - Param is the content of the matched parameter
+type Match struct {
+  Path  Path
+  Node  *Node
+  Param Nodes
+}
+
+func (m Match) StringRepresentation() string {
+  if m.Node != nil {
+    return m.Node.StringRepresentation()
+  }
+  return m.Param.StringRepresentation()
+}
+
+// CompileSelector parses a selector expression
+func CompileSelector(source string) (*Selector, error) {
+  sc := &selectorScanner{src: []rune(source)}
+  parts, err := sc.parse()
+  if err != nil {
+    return nil, err
+  }
+  return &Selector{source: source, parts: parts}, nil
+}
+
+// MustCompileSelector is like CompileSelector but panics if the expression is invalid
+func MustCompileSelector(source string) *Selector {
+  s, err := CompileSelector(source)
+  if err != nil {
+    panic(err)
+  }
+  return s
+}
+
+// Select returns every element of the tree matching the selector, in walk order
+func (s *Selector) Select(ns Nodes) []Match {
+  all := make([]*element, 0)
+  buildElements(ns, nil, nil, Step{Param: -1}, &all)
+
+  ret := make([]Match, 0)
+  last := len(s.parts) - 1
+  for _, el := range all {
+    if s.matches(last, el) {
+      ret = append(ret, Match{Path: el.path, Node: el.node, Param: el.content})
+    }
+  }
+  return ret
+}
+
+// Select compiles the query and runs it over the nodes
+func Select(ns Nodes, query string) ([]Match, error) {
+  s, err := CompileSelector(query)
+  if err != nil {
+    return nil, err
+  }
+  return s.Select(ns), nil
+}
+
+// element is either a node or a parameter of a node
+type element struct {
+  node     *Node
+  param    string
+  content  Nodes
+  parent   *element
+  siblings []*element
+  index    int
+  path     Path
+}
+
+func buildElements(ns Nodes, parent *element, path Path, s Step, all *[]*element) {
+  siblings := make([]*element, 0, len(ns))
+  for ix := range ns {
+    s.Index = ix
+    el := &element{node: &ns[ix], parent: parent, index: ix, path: path.child(s)}
+    siblings = append(siblings, el)
+  }
+  for _, el := range siblings {
+    el.siblings = siblings
+    *all = append(*all, el)
+    buildParams(el, all)
+  }
+}
+
+func buildParams(el *element, all *[]*element) {
+  n := el.node
+  params := make([]*element, 0, len(n.NamedParams)+len(n.Params))
+  steps := make([]Step, 0, cap(params))
+  for _, k := range n.namedKeys() {
+    params = append(params, &element{param: k, content: n.NamedParams[k], parent: el})
+    steps = append(steps, Step{Named: k, Param: -1})
+  }
+  for ix, param := range n.Params {
+    params = append(params, &element{param: strconv.Itoa(ix), content: param, parent: el})
+    steps = append(steps, Step{Param: ix})
+  }
+  for ix, p := range params {
+    p.siblings = params
+    p.index = ix
+    step := steps[ix]
+    step.Index = -1
+    p.path = el.path.child(step)
+    *all = append(*all, p)
+    buildElements(p.content, p, el.path, steps[ix], all)
+  }
+}
+
+func (s *Selector) matches(i int, el *element) bool {
+  c := s.parts[i]
+  if !c.matchElement(el) {
+    return false
+  }
+  if i == 0 {
+    return true
+  }
+  switch c.combinator {
+  case '>':
+    p := el.parent
+    if p != nil && p.node == nil && s.parts[i-1].typ != "param" {
+      p = p.parent
+    }
+    return p != nil && s.matches(i-1, p)
+  case '~':
+    for j := el.index - 1; j >= 0; j-- {
+      if s.matches(i-1, el.siblings[j]) {
+        return true
+      }
+    }
+  default:
+    for a := el.parent; a != nil; a = a.parent {
+      if s.matches(i-1, a) {
+        return true
+      }
+    }
+  }
+  return false
+}
+
+func (c compound) matchElement(el *element) bool {
+  if c.typ == "param" {
+    if el.node != nil {
+      return false
+    }
+    for _, f := range c.attrs {
+      if f.op == opExists && f.key != "name" && f.key != "text" {
+        if f.key != el.param {
+          return false
+        }
+        continue
+      }
+      v, ok := paramAttr(el, f.key)
+      if !f.test(v, ok) {
+        return false
+      }
+    }
+    return true
+  }
+
+  if el.node == nil {
+    return false
+  }
+  if c.typ != "*" && selectorTypes[c.typ] != el.node.Typ {
+    return false
+  }
+  for _, f := range c.attrs {
+    v, ok := nodeAttr(el.node, f.key)
+    if !f.test(v, ok) {
+      return false
+    }
+  }
+  return true
+}
+
+func paramAttr(el *element, key string) (string, bool) {
+  switch key {
+  case "name":
+    return el.param, true
+  case "text":
+    return strings.TrimSpace(el.content.StringRepresentation()), true
+  }
+  return "", false
+}
+
+func nodeAttr(n *Node, key string) (string, bool) {
+  switch key {
+  case "name":
+    switch n.Typ {
+    case NodeTemplate:
+      return strings.TrimSpace(n.StringParam("name")), true
+    case NodeLink, NodeELink:
+      return n.StringParamOrEmpty("link"), true
+    }
+    return "", false
+  case "text":
+    return strings.TrimSpace(n.StringRepresentation()), true
+  case "val":
+    return n.Val, true
+  }
+  if _, ok := n.NamedParams[key]; ok {
+    return n.StringParamOrEmpty(key), true
+  }
+  return "", false
+}
+
+type selectorScanner struct {
+  src []rune
+  pos int
+}
+
+func (sc *selectorScanner) errorf(format string, params ...interface{}) error {
+  return fmt.Errorf("invalid selector %q at offset %d: %s", string(sc.src), sc.pos, fmt.Sprintf(format, params...))
+}
+
+func (sc *selectorScanner) peek() rune {
+  if sc.pos >= len(sc.src) {
+    return 0
+  }
+  return sc.src[sc.pos]
+}
+
+func (sc *selectorScanner) skipSpaces() bool {
+  skipped := false
+  for sc.pos < len(sc.src) && unicode.IsSpace(sc.src[sc.pos]) {
+    sc.pos += 1
+    skipped = true
+  }
+  return skipped
+}
+
+func (sc *selectorScanner) parse() ([]compound, error) {
+  parts := make([]compound, 0)
+  sc.skipSpaces()
+  for sc.pos < len(sc.src) {
+    var comb rune
+    if len(parts) > 0 {
+      spaced := sc.skipSpaces()
+      switch r := sc.peek(); {
+      case r == 0:
+        return parts, nil
+      case r == '>' || r == '~':
+        comb = r
+        sc.pos += 1
+        sc.skipSpaces()
+      case spaced:
+        comb = ' '
+      default:
+        return nil, sc.errorf("unexpected %q", r)
+      }
+    }
+    c, err := sc.parseCompound()
+    if err != nil {
+      return nil, err
+    }
+    c.combinator = comb
+    parts = append(parts, c)
+  }
+  if len(parts) == 0 {
+    return nil, sc.errorf("empty selector")
+  }
+  return parts, nil
+}
+
+func (sc *selectorScanner) parseCompound() (compound, error) {
+  c := compound{typ: "*", attrs: make([]attrFilter, 0)}
+  start := sc.pos
+  for r := sc.peek(); r == '*' || unicode.IsLetter(r); r = sc.peek() {
+    sc.pos += 1
+  }
+  if sc.pos > start {
+    c.typ = string(sc.src[start:sc.pos])
+    if _, ok := selectorTypes[c.typ]; !ok && c.typ != "*" && c.typ != "param" {
+      sc.pos = start
+      return c, sc.errorf("unknown type %q", c.typ)
+    }
+  } else if sc.peek() != '[' {
+    return c, sc.errorf("expected a type or an attribute, got %q", sc.peek())
+  }
+
+  for sc.peek() == '[' {
+    sc.pos += 1
+    f, err := sc.parseAttr()
+    if err != nil {
+      return c, err
+    }
+    c.attrs = append(c.attrs, f)
+  }
+  return c, nil
+}
+
+func (sc *selectorScanner) parseAttr() (attrFilter, error) {
+  f := attrFilter{op: opExists}
+  sc.skipSpaces()
+  start := sc.pos
+  for r := sc.peek(); r != 0 && !strings.ContainsRune("]=!~^$*", r); r = sc.peek() {
+    sc.pos += 1
+  }
+  f.key = strings.TrimSpace(string(sc.src[start:sc.pos]))
+  if f.key == "" {
+    return f, sc.errorf("missing attribute name")
+  }
+
+  if sc.peek() != ']' {
+    found := false
+    for _, o := range attrOps {
+      if strings.HasPrefix(string(sc.src[sc.pos:]), o.s) {
+        f.op = o.op
+        sc.pos += len([]rune(o.s))
+        found = true
+        break
+      }
+    }
+    if !found {
+      return f, sc.errorf("unknown operator")
+    }
+    v, err := sc.parseValue()
+    if err != nil {
+      return f, err
+    }
+    f.value = v
+    if f.op == opMatches {
+      if f.re, err = regexp.Compile(v); err != nil {
+        return f, sc.errorf("%s", err)
+      }
+    }
+  }
+
+  if sc.peek() != ']' {
+    return f, sc.errorf("expected ']'")
+  }
+  sc.pos += 1
+  return f, nil
+}
+
+func (sc *selectorScanner) parseValue() (string, error) {
+  sc.skipSpaces()
+  quote := sc.peek()
+  if quote != '"' && quote != '\'' {
+    start := sc.pos
+    for r := sc.peek(); r != 0 && r != ']'; r = sc.peek() {
+      sc.pos += 1
+    }
+    return strings.TrimSpace(string(sc.src[start:sc.pos])), nil
+  }
+
+  sc.pos += 1
+  v := make([]rune, 0)
+  for {
+    r := sc.peek()
+    switch r {
+    case 0:
+      return "", sc.errorf("unterminated string")
+    case '\\':
+      // only the quote and the backslash are escaped, regexp classes like \d are kept
+      sc.pos += 1
+      switch next := sc.peek(); next {
+      case 0:
+        return "", sc.errorf("unterminated string")
+      case quote, '\\':
+        v = append(v, next)
+      default:
+        v = append(v, '\\', next)
+      }
+    case quote:
+      sc.pos += 1
+      sc.skipSpaces()
+      return string(v), nil
+    default:
+      v = append(v, r)
+    }
+    sc.pos += 1
+  }
+}
@@ -0,0 +1,57 @@
+package wikimediaparser
+
+import (
+  "testing"
+)
+
+const selectorDoc = "==== [[Alain]] ====\n{{Citation|citation=Aimer, c’est trouver sa richesse hors de soi.}}\n{{Réf Livre|titre=Éléments de philosophie|auteur=[[Alain]]|année=1980}}\n{{w|Victor Hugo}}\n"
+
+func TestSelectRegexp(t *testing.T) {
+  p := Parse(Tokenize(selectorDoc))
+  m, err := Select(p, `template[name~="(?i)citation"]`)
+  if err != nil {
+    t.Fatal(err)
+  }
+  assertEqual(t, "Match count", 1, len(m))
+  assertEqual(t, "Match path", "/2", m[0].Path.String())
+}
+
+func TestSelectParamChild(t *testing.T) {
+  p := Parse(Tokenize(selectorDoc))
+  m := MustCompileSelector(`template[name="Réf Livre"] > param[auteur] link`).Select(p)
+  assertEqual(t, "Match count", 1, len(m))
+  assertEqual(t, "Match text", "Alain", m[0].StringRepresentation())
+  assertEqual(t, "Match path", "/4/auteur/0", m[0].Path.String())
+}
+
+func TestSelectParam(t *testing.T) {
+  p := Parse(Tokenize(selectorDoc))
+  m := MustCompileSelector(`template[name="Réf Livre"] > param[année]`).Select(p)
+  assertEqual(t, "Match count", 1, len(m))
+  assertEqual(t, "Param value", "1980", m[0].StringRepresentation())
+  assertEqual(t, "Param path", "/4/année", m[0].Path.String())
+}
+
+func TestSelectSibling(t *testing.T) {
+  p := Parse(Tokenize(selectorDoc))
+  m := MustCompileSelector(`title[level=4] ~ template`).Select(p)
+  assertEqual(t, "Templates following the title", 3, len(m))
+
+  m = MustCompileSelector(`title[level=3] ~ template`).Select(p)
+  assertEqual(t, "Templates following a missing title", 0, len(m))
+}
+
+func TestSelectChildThroughParams(t *testing.T) {
+  p := Parse(Tokenize(selectorDoc))
+  assertEqual(t, "Links directly in a template", 1, len(MustCompileSelector("template > link").Select(p)))
+  assertEqual(t, "Links anywhere", 2, len(MustCompileSelector("link").Select(p)))
+  assertEqual(t, "Templates with an author", 1, len(MustCompileSelector("[auteur]").Select(p)))
+}
+
+func TestSelectorErrors(t *testing.T) {
+  for _, s := range []string{"", "frob", "template[", "template[name=\"x]", "template[name~=\"(\"]", "template >"} {
+    if _, err := CompileSelector(s); err == nil {
+      t.Errorf("Expected an error for selector %q", s)
+    }
+  }
+}
@@ -0,0 +1,53 @@
+package wikimediaparser
+
+import (
+  "fmt"
+  "strings"
+  "unicode/utf8"
+)
+
+// Span is a range of bytes [Start, End) in the parsed source
+type Span struct {
+  Start int `json:"start"`
+  End   int `json:"end"`
+}
+
+func (s Span) String() string {
+  return fmt.Sprintf("%d-%d", s.Start, s.End)
+}
+
+// IsZero reports whether the span was never set, like for nodes built by hand
+func (s Span) IsZero() bool {
+  return s.Start == 0 && s.End == 0
+}
+
+// Span returns the span covered by the nodes
+func (ns Nodes) Span() Span {
+  ret := Span{}
+  for _, n := range ns {
+    if n.Span.IsZero() {
+      continue
+    }
+    if ret.IsZero() || n.Span.Start < ret.Start {
+      ret.Start = n.Span.Start
+    }
+    if n.Span.End > ret.End {
+      ret.End = n.Span.End
+    }
+  }
+  return ret
+}
+
+// LineColumn converts a byte offset of source into a 1-based line and rune column
+func LineColumn(source string, offset int) (line int, column int) {
+  if offset > len(source) {
+    offset = len(source)
+  }
+  if offset < 0 {
+    offset = 0
+  }
+  before := source[:offset]
+  line = strings.Count(before, "\n") + 1
+  column = utf8.RuneCountInString(before[strings.LastIndex(before, "\n")+1:]) + 1
+  return line, column
+}
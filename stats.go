@@ -0,0 +1,232 @@
+package wikimediaparser
+
+import (
+  "encoding/csv"
+  "encoding/json"
+  "io"
+  "regexp"
+  "sort"
+  "strconv"
+  "strings"
+  "unicode"
+  "unicode/utf8"
+)
+
+// Stats aggregates the markup usage of many pages
+//    - Templates counts the calls per template name, first letter upper-cased
+//    - Params counts the parameter names per template, case kept to spot ISBN vs isbn
+//    - LinkNamespaces counts the internal links per namespace, "" for the main namespace and the unknown prefixes
+//    - Headings counts the headings per depth
+//    - Tags counts the opening HTML or extension tags, like poem or ref, outside of the comments and nowiki
+//    - RecoveredPages and Recoveries count the pages and sections the parser had to skip
+//    - SkippedPages counts the pages the parser gave up on, their markup is not counted
+//    - Namespace resolves the link prefixes to namespace names, like dump.SiteInfo.NamespaceName, nil for none
+type Stats struct {
+  Pages          int                       `json:"pages"`
+  SkippedPages   int                       `json:"skipped_pages"`
+  RecoveredPages int                       `json:"recovered_pages"`
+  Recoveries     int                       `json:"recoveries"`
+  Templates      map[string]int            `json:"templates"`
+  Params         map[string]map[string]int `json:"params"`
+  LinkNamespaces map[string]int            `json:"link_namespaces"`
+  Headings       map[int]int               `json:"headings"`
+  Tags           map[string]int            `json:"tags"`
+
+  Namespace func(prefix string) (string, bool) `json:"-"`
+}
+
+func NewStats() *Stats {
+  return &Stats{
+    Templates:      make(map[string]int),
+    Params:         make(map[string]map[string]int),
+    LinkNamespaces: make(map[string]int),
+    Headings:       make(map[int]int),
+    Tags:           make(map[string]int),
+  }
+}
+
+var openingTag = regexp.MustCompile(`<([a-zA-Z][a-zA-Z0-9]*)[\s/>]`)
+
+// unparsedMarkup matches the comments and the content of the nowiki tags,
+// up to the end of the page when they are not closed
+var unparsedMarkup = regexp.MustCompile(`(?is)<!--.*?(?:-->|$)|(<nowiki\s*>).*?(?:</nowiki\s*>|$)`)
+
+// Add parses a page source and counts its markup
+func (s *Stats) Add(source string) {
+  s.AddWithOptions(source, ParseOptions{})
+}
+
+// AddWithOptions is Add with a parse budget. A page exceeding it, or breaking
+// the parser, is counted as skipped and its error returned.
+func (s *Stats) AddWithOptions(source string, opts ParseOptions) error {
+  nodes, diagnostics, err := parseChecked(Tokenize(source), opts)
+  s.Pages += 1
+  if err != nil {
+    s.SkippedPages += 1
+    return err
+  }
+
+  recoveries := 0
+  for _, d := range diagnostics {
+    if d.Severity == SeverityError {
+      recoveries += 1
+    }
+  }
+  s.Recoveries += recoveries
+  if recoveries > 0 {
+    s.RecoveredPages += 1
+  }
+
+  // a nowiki tag is counted, not the tags it escapes
+  tagged := unparsedMarkup.ReplaceAllString(source, "$1")
+  for _, m := range openingTag.FindAllStringSubmatch(tagged, -1) {
+    s.Tags[strings.ToLower(m[1])] += 1
+  }
+
+  Inspect(nodes, func(n *Node, parent *Node, path Path) bool {
+    if n == nil {
+      return true
+    }
+    switch n.Typ {
+    case NodeTemplate:
+      name := normalizeTitle(n.StringParamOrEmpty("name"))
+      if name == "" {
+        return true
+      }
+      s.Templates[name] += 1
+      if s.Params[name] == nil {
+        s.Params[name] = make(map[string]int)
+      }
+      for _, k := range unusedParams(n, nil) {
+        s.Params[name][k] += 1
+      }
+    case NodeLink:
+      s.LinkNamespaces[s.linkNamespace(n.StringParamOrEmpty("link"))] += 1
+    case NodeTitle:
+      level, _ := strconv.Atoi(n.StringParamOrEmpty("level"))
+      s.Headings[level] += 1
+    }
+    return true
+  })
+  return nil
+}
+
+// Merge adds the counts of o, to combine the stats computed on parts of a dump
+func (s *Stats) Merge(o *Stats) {
+  s.Pages += o.Pages
+  s.SkippedPages += o.SkippedPages
+  s.RecoveredPages += o.RecoveredPages
+  s.Recoveries += o.Recoveries
+  mergeCounts(s.Templates, o.Templates)
+  mergeCounts(s.LinkNamespaces, o.LinkNamespaces)
+  mergeCounts(s.Tags, o.Tags)
+  for k, v := range o.Headings {
+    s.Headings[k] += v
+  }
+  for name, params := range o.Params {
+    if s.Params[name] == nil {
+      s.Params[name] = make(map[string]int)
+    }
+    mergeCounts(s.Params[name], params)
+  }
+}
+
+func mergeCounts(dst map[string]int, src map[string]int) {
+  for k, v := range src {
+    dst[k] += v
+  }
+}
+
+// RecoveryRate is the share of pages on which the parser had to skip something
+func (s *Stats) RecoveryRate() float64 {
+  if s.Pages == 0 {
+    return 0
+  }
+  return float64(s.RecoveredPages) / float64(s.Pages)
+}
+
+// WriteJSON writes the stats as a JSON object, with the recovery rate
+func (s *Stats) WriteJSON(w io.Writer) error {
+  enc := json.NewEncoder(w)
+  enc.SetIndent("", "  ")
+  return enc.Encode(struct {
+    *Stats
+    RecoveryRate float64 `json:"recovery_rate"`
+  }{s, s.RecoveryRate()})
+}
+
+// WriteCSV writes one metric,key,subkey,count row per counter, the most
+// used first within each metric
+func (s *Stats) WriteCSV(w io.Writer) error {
+  cw := csv.NewWriter(w)
+  rows := [][]string{
+    {"metric", "key", "subkey", "count"},
+    {"pages", "", "", strconv.Itoa(s.Pages)},
+    {"skipped_pages", "", "", strconv.Itoa(s.SkippedPages)},
+    {"recovered_pages", "", "", strconv.Itoa(s.RecoveredPages)},
+    {"recoveries", "", "", strconv.Itoa(s.Recoveries)},
+  }
+  counterRows := func(metric string, key string, counts map[string]int) {
+    for _, k := range sortedByCount(counts) {
+      if key == "" {
+        rows = append(rows, []string{metric, k, "", strconv.Itoa(counts[k])})
+      } else {
+        rows = append(rows, []string{metric, key, k, strconv.Itoa(counts[k])})
+      }
+    }
+  }
+  counterRows("template", "", s.Templates)
+  for _, name := range sortedByCount(s.Templates) {
+    counterRows("param", name, s.Params[name])
+  }
+  counterRows("link_namespace", "", s.LinkNamespaces)
+  levels := make([]int, 0, len(s.Headings))
+  for level := range s.Headings {
+    levels = append(levels, level)
+  }
+  sort.Ints(levels)
+  for _, level := range levels {
+    rows = append(rows, []string{"heading", strconv.Itoa(level), "", strconv.Itoa(s.Headings[level])})
+  }
+  counterRows("tag", "", s.Tags)
+
+  cw.WriteAll(rows)
+  return cw.Error()
+}
+
+func sortedByCount(counts map[string]int) []string {
+  keys := make([]string, 0, len(counts))
+  for k := range counts {
+    keys = append(keys, k)
+  }
+  sort.Slice(keys, func(i, j int) bool {
+    if counts[keys[i]] != counts[keys[j]] {
+      return counts[keys[i]] > counts[keys[j]]
+    }
+    return keys[i] < keys[j]
+  })
+  return keys
+}
+
+// normalizeTitle normalizes a page or template name the way MediaWiki does:
+// blanks and underscores folded, first letter upper-cased
+func normalizeTitle(name string) string {
+  name = CanonicalTemplateName(name)
+  first, size := utf8.DecodeRuneInString(name)
+  if size == 0 {
+    return name
+  }
+  return string(unicode.ToUpper(first)) + name[size:]
+}
+
+// linkNamespace returns the namespace of a link target, "" when its prefix
+// is not a namespace, like Star Wars in [[Star Wars: A New Hope]]
+func (s *Stats) linkNamespace(target string) string {
+  target = strings.TrimPrefix(strings.TrimSpace(target), ":")
+  ix := strings.Index(target, ":")
+  if ix <= 0 || s.Namespace == nil {
+    return ""
+  }
+  name, _ := s.Namespace(target[:ix])
+  return name
+}
@@ -0,0 +1,105 @@
+package wikimediaparser
+
+import (
+  "bytes"
+  "strings"
+  "testing"
+)
+
+// namespaces resolves the category namespace only, as a site info would
+func namespaces(prefix string) (string, bool) {
+  if strings.ToLower(prefix) == "catégorie" || strings.ToLower(prefix) == "category" {
+    return "Catégorie", true
+  }
+  return "", false
+}
+
+func TestStats(t *testing.T) {
+  s := NewStats()
+  s.Namespace = namespaces
+  s.Add("== Citations ==\n{{citation|<poem>Un</poem>}}\n{{Réf Livre|titre=A|ISBN=1}}\n[[Catégorie:Amour]] [[w:Victor Hugo|Hugo]] [[Amour]]")
+  s.Add("=== Section ===\n{{Réf_Livre|titre=B|isbn=2}}<br />\n{{w|Hugo}}")
+  s.Add("{{citation|sans fin\n")
+
+  assertEqual(t, "pages", 3, s.Pages)
+  assertEqual(t, "recovered pages", 1, s.RecoveredPages)
+  assertEqual(t, "template names", 2, s.Templates["Réf Livre"])
+  assertEqual(t, "lower case first letter", 2, s.Templates["Citation"])
+  assertEqual(t, "ISBN", 1, s.Params["Réf Livre"]["ISBN"])
+  assertEqual(t, "isbn", 1, s.Params["Réf Livre"]["isbn"])
+  assertEqual(t, "titre", 2, s.Params["Réf Livre"]["titre"])
+  assertEqual(t, "positional", 1, s.Params["W"]["#0"])
+  assertEqual(t, "main namespace and interwiki", 2, s.LinkNamespaces[""])
+  assertEqual(t, "category", 1, s.LinkNamespaces["Catégorie"])
+  assertEqual(t, "level 2", 1, s.Headings[2])
+  assertEqual(t, "level 3", 1, s.Headings[3])
+  assertEqual(t, "poem", 1, s.Tags["poem"])
+  assertEqual(t, "br", 1, s.Tags["br"])
+
+  other := NewStats()
+  other.Add("{{w|Hugo}}")
+  s.Merge(other)
+  assertEqual(t, "merged pages", 4, s.Pages)
+  assertEqual(t, "merged templates", 2, s.Templates["W"])
+  assertEqual(t, "recovery rate", 0.25, s.RecoveryRate())
+}
+
+func TestStatsLinkNamespaces(t *testing.T) {
+  s := NewStats()
+  s.Add("[[Category:Amour]] [[Star Wars: A New Hope]]")
+  assertEqual(t, "no namespaces", 2, s.LinkNamespaces[""])
+
+  s = NewStats()
+  s.Namespace = namespaces
+  s.Add("[[Category:Amour]] [[:catégorie:Amitié]] [[Star Wars: A New Hope]] [[Jeanne d'Arc]]")
+  assertEqual(t, "known namespace", 2, s.LinkNamespaces["Catégorie"])
+  assertEqual(t, "unknown prefixes", 2, s.LinkNamespaces[""])
+  assertEqual(t, "namespaces", 2, len(s.LinkNamespaces))
+}
+
+func TestStatsTags(t *testing.T) {
+  s := NewStats()
+  s.Add("<ref>a</ref> <!-- <ref>b</ref> <poem> -->\n<nowiki><ref>c</ref> <br /></nowiki> <NoWiki/> <br>\n<!-- <small>")
+  assertEqual(t, "ref", 1, s.Tags["ref"])
+  assertEqual(t, "br", 1, s.Tags["br"])
+  assertEqual(t, "nowiki", 2, s.Tags["nowiki"])
+  assertEqual(t, "tags", 3, len(s.Tags))
+}
+
+func TestStatsOutput(t *testing.T) {
+  s := NewStats()
+  s.Add("{{w|Hugo}} {{w|Zola}} {{citation|x}}")
+
+  var out bytes.Buffer
+  s.WriteCSV(&out)
+  lines := strings.Split(out.String(), "\n")
+  assertEqual(t, "header", "metric,key,subkey,count", lines[0])
+  assertEqual(t, "most used first", "template,W,,2", lines[5])
+  assertEqual(t, "params", "param,W,#0,2", lines[7])
+
+  out.Reset()
+  s.WriteJSON(&out)
+  if !strings.Contains(out.String(), `"recovery_rate": 0`) || !strings.Contains(out.String(), `"W": 2`) {
+    t.Errorf("Unexpected JSON output %s", out.String())
+  }
+}
+
+func TestStatsBudget(t *testing.T) {
+  s := NewStats()
+  err := s.AddWithOptions("{{w|Hugo}} {{w|Zola}} {{citation|x}}", ParseOptions{MaxSteps: 5})
+  if _, ok := err.(*BudgetError); !ok {
+    t.Fatalf("Expected a budget error, got %v", err)
+  }
+  assertEqual(t, "pages", 1, s.Pages)
+  assertEqual(t, "skipped pages", 1, s.SkippedPages)
+  assertEqual(t, "no templates", 0, len(s.Templates))
+
+  if err := s.AddWithOptions("{{w|Hugo}}", ParseOptions{MaxSteps: 1000}); err != nil {
+    t.Fatalf("Unexpected error %s", err)
+  }
+  assertEqual(t, "counted", 1, s.Templates["W"])
+
+  other := NewStats()
+  other.Merge(s)
+  assertEqual(t, "merged skipped pages", 1, other.SkippedPages)
+}
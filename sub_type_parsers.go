@@ -2,7 +2,6 @@ package wikimediaparser
 
 import (
   "fmt"
-  "github.com/golang/glog"
 )
 
 type markup int
@@ -16,7 +15,7 @@ const (
 func (p *parser) parseTitle() (ret Node) {
   exitSequence := make([]token, 0)
 
-  glog.V(2).Infoln("Parsing title 1", p.items[p.pos:])
+  p.log("Parsing title 1 %s", p.items[p.pos:])
   item := p.eatCurrentItem()
   level := 0
 
@@ -33,7 +32,7 @@ func (p *parser) parseTitle() (ret Node) {
   if level > 0 {
     p.backup(1)
   }
-  glog.V(2).Infoln("Parsing title", p.items[p.pos:])
+  p.log("Parsing title %s", p.items[p.pos:])
   ret = Node{Typ: NodeTitle, NamedParams: make(map[string]Nodes), Params: make([]Nodes, 0)}
   ret.NamedParams["level"] = Nodes{Node{Typ: NodeText, Val: fmt.Sprintf("%d", level)}}
   content, consumed := ParseWithEnv(fmt.Sprintf("%s::Title(%d)", p.name, level),
@@ -47,17 +46,21 @@ func (p *parser) parseTitle() (ret Node) {
   // Only one invalid node in return, insert instead of element here
   if len(content) == 1 && content[0].Typ == NodeInvalid {
     ret = content[0]
-    glog.V(2).Infoln("will insert an invalid node", ret)
+    p.log("will insert an invalid node %s", &ret)
   }
 
   // Be cool with next reader, give him the LF
   p.consume(consumed - 2)
-  glog.V(2).Infoln("And now", p.items[p.pos:], consumed)
+  p.log("And now %s %d", p.items[p.pos:], consumed)
 
   return ret
 }
 
 func (p *parser) ParseLink() Node {
+  // like MediaWiki, a [[ not closed on its line is plain text, see closedOnLine
+  if !closedOnLine(p.items, p.pos) {
+    return Node{Typ: NodeText, Val: leftLink}
+  }
   ret := Node{Typ: NodeLink, NamedParams: make(map[string]Nodes), Params: make([]Nodes, 0)}
 
   p.eat(linkStart)
@@ -76,6 +79,10 @@ func (p *parser) ParseLink() Node {
 }
 
 func (p *parser) ParseELink() Node {
+  // like MediaWiki, a [ not closed on its line is plain text, see closedOnLine
+  if !closedOnLine(p.items, p.pos) {
+    return Node{Typ: NodeText, Val: leftExtLink}
+  }
   ret := Node{Typ: NodeELink, NamedParams: make(map[string]Nodes), Params: make([]Nodes, 0)}
 
   p.eat(tokenELnkStart)
@@ -98,9 +105,37 @@ func (p *parser) ParseELink() Node {
   return ret
 }
 
+// closedOnLine reports whether the [[ or [ token at ix is closed before the
+// end of its line, counting the nested open tokens. The links left open used
+// to take the rest of the page as their parameters before falling back to
+// text, which the stray brackets of a long page made cost over 100M steps.
+// The lint reports the brackets it rejects.
+func closedOnLine(items []item, ix int) bool {
+  open := items[ix].Typ
+  close := linkEnd
+  if open == tokenELnkStart {
+    close = tokenELnkEnd
+  }
+  depth := 1
+  for ix += 1; ix < len(items); ix++ {
+    switch items[ix].Typ {
+    case open:
+      depth += 1
+    case close:
+      depth -= 1
+      if depth == 0 {
+        return true
+      }
+    case tokenLF, tokenEOF:
+      return false
+    }
+  }
+  return false
+}
+
 func (p *parser) ParseTemplate() (ret Node) {
   ret = Node{Typ: NodeTemplate, NamedParams: make(map[string]Nodes), Params: make([]Nodes, 0)}
-  glog.V(2).Infoln("Parsing a template")
+  p.log("Parsing a template")
 
   p.eat(templateStart)
   name, consumed := ParseWithEnv(fmt.Sprintf("%s::Template", p.name),
@@ -109,13 +144,13 @@ func (p *parser) ParseTemplate() (ret Node) {
     []token{itemPipe, templateEnd}, nil, ignoreSectionBehavior)
   ret.NamedParams["name"] = name
   p.consume(consumed)
-  glog.V(2).Infof("Found template %s, now scanning sub arguments from %s", name.String(), p.items[p.pos:])
+  p.log("Found template %s, now scanning sub arguments from %s", name, p.items[p.pos:])
   consumed = ScanSubArgumentsUntil(fmt.Sprintf("template-%s", name), p, &ret, templateEnd)
 
   // outer loop will eat last item automatically
   p.consume(consumed - 1)
 
-  glog.V(2).Infoln("Parsed a template")
+  p.log("Parsed a template")
   return ret
 }
 
@@ -0,0 +1,214 @@
+package wikimediaparser
+
+import (
+  "regexp"
+  "strings"
+  "unicode"
+  "unicode/utf8"
+)
+
+// TemplateRenderer renders a template node for a TextRenderer
+type TemplateRenderer func(r *TextRenderer, n *Node) string
+
+type lineBreakMode int
+
+const (
+  KeepLineBreaks = lineBreakMode(iota)
+  SpaceLineBreaks
+  SlashLineBreaks
+)
+
+// TextRenderer turns Nodes into plain text.
+//    - Templates maps canonical template names to their renderer, see Register
+//    - Fallback renders the templates missing from the registry (nil renders nothing)
+//    - CollapseWhitespace folds runs of blanks, trims lines and drops empty lines
+//    - LineBreaks tells what to do with line breaks (including <br /> tags)
+//    - StripEmphasis drops the '' and ''' of the italics and the bold
+type TextRenderer struct {
+  Templates          map[string]TemplateRenderer
+  Fallback           TemplateRenderer
+  CollapseWhitespace bool
+  LineBreaks         lineBreakMode
+  StripEmphasis      bool
+}
+
+// NewTextRenderer returns a renderer knowing the common wikiquote templates.
+// Unknown templates render as their first positional parameter, like StringRepresentation.
+func NewTextRenderer() *TextRenderer {
+  r := &TextRenderer{
+    Templates: make(map[string]TemplateRenderer),
+    Fallback:  renderFirstParam,
+  }
+  r.Register("w", renderWikipediaLink)
+  r.Register("lang", renderLang)
+  r.Register("date", renderDate)
+  r.Register("citation", renderCitation)
+  r.Register("tab", renderConstant("\t"))
+  r.Register("!", renderConstant("|"))
+  return r
+}
+
+var defaultTextRenderer = NewTextRenderer()
+
+// RenderText renders the nodes with the default TextRenderer
+func RenderText(ns Nodes) string {
+  return defaultTextRenderer.Render(ns)
+}
+
+// Register sets the renderer for a template name
+func (r *TextRenderer) Register(name string, fn TemplateRenderer) {
+  r.Templates[CanonicalTemplateName(name)] = fn
+}
+
+// Render renders the nodes and applies the whitespace options
+func (r *TextRenderer) Render(ns Nodes) string {
+  out := brTag.ReplaceAllString(r.render(ns), "\n")
+  if r.StripEmphasis {
+    out = emphasisMarkup.ReplaceAllStringFunc(out, stripEmphasis)
+  }
+
+  if r.CollapseWhitespace {
+    lines := make([]string, 0)
+    for _, line := range strings.Split(out, "\n") {
+      line = strings.Join(strings.FieldsFunc(line, unicode.IsSpace), " ")
+      if line != "" {
+        lines = append(lines, line)
+      }
+    }
+    out = strings.Join(lines, "\n")
+  }
+
+  switch r.LineBreaks {
+  case SpaceLineBreaks:
+    out = strings.Replace(out, "\n", " ", -1)
+  case SlashLineBreaks:
+    out = strings.Replace(out, "\n", " / ", -1)
+  }
+  return out
+}
+
+// render renders the nodes without touching whitespace, for use in TemplateRenderers
+func (r *TextRenderer) render(ns Nodes) string {
+  output := ""
+  for ix := range ns {
+    output += r.RenderNode(&ns[ix])
+  }
+  return output
+}
+
+// RenderNode renders a single node without touching whitespace
+func (r *TextRenderer) RenderNode(n *Node) string {
+  switch n.Typ {
+  case NodeText, NodeInvalid:
+    return n.Val
+  case NodeLink, NodeELink:
+    if len(n.Params) > 0 {
+      return r.render(n.Params[len(n.Params)-1])
+    }
+    return r.render(n.NamedParams["link"])
+  case NodeTitle:
+    return strings.TrimSpace(r.render(n.NamedParams["title"]))
+  case NodeTemplate:
+    fn, ok := r.Templates[CanonicalTemplateName(n.StringParam("name"))]
+    if !ok {
+      fn = r.Fallback
+    }
+    if fn == nil {
+      return ""
+    }
+    return fn(r, n)
+  }
+  return ""
+}
+
+// Param renders the positional parameter ix of a node, trimmed
+func (r *TextRenderer) Param(n *Node, ix int) string {
+  if ix < 0 || ix >= len(n.Params) {
+    return ""
+  }
+  return strings.TrimSpace(r.render(n.Params[ix]))
+}
+
+// Named renders the first existing named parameter among keys, trimmed
+func (r *TextRenderer) Named(n *Node, keys ...string) string {
+  for _, k := range keys {
+    if v, ok := n.NamedParams[k]; ok {
+      return strings.TrimSpace(r.render(v))
+    }
+  }
+  return ""
+}
+
+var brTag = regexp.MustCompile(`(?i)<br\s*/?>`)
+
+var emphasisMarkup = regexp.MustCompile(`'{2,}`)
+
+// stripEmphasis keeps the apostrophes of a run that are text, as the HTMLWriter does
+func stripEmphasis(m string) string {
+  switch {
+  case len(m) <= 3:
+    return ""
+  case len(m) == 4:
+    return "'"
+  }
+  return strings.Repeat("'", len(m)-5)
+}
+
+// CanonicalTemplateName applies the MediaWiki title rules: underscores are
+// spaces, blanks are folded and the first letter is case insensitive.
+func CanonicalTemplateName(name string) string {
+  name = strings.Join(strings.Fields(strings.Replace(name, "_", " ", -1)), " ")
+  first, size := utf8.DecodeRuneInString(name)
+  if size == 0 {
+    return name
+  }
+  return string(unicode.ToLower(first)) + name[size:]
+}
+
+func renderConstant(s string) TemplateRenderer {
+  return func(r *TextRenderer, n *Node) string {
+    return s
+  }
+}
+
+func renderFirstParam(r *TextRenderer, n *Node) string {
+  if len(n.Params) > 0 {
+    return r.render(n.Params[0])
+  }
+  return ""
+}
+
+// {{w|Victor Hugo}} or {{w|Victor Hugo|Hugo}}
+func renderWikipediaLink(r *TextRenderer, n *Node) string {
+  if label := r.Param(n, 1); label != "" {
+    return label
+  }
+  return r.Param(n, 0)
+}
+
+// {{lang|en|text}} or {{lang|en|texte=text}}
+func renderLang(r *TextRenderer, n *Node) string {
+  if text := r.Named(n, "texte", "text"); text != "" {
+    return text
+  }
+  return r.Param(n, 1)
+}
+
+// {{date|1|mars|2010}}
+func renderDate(r *TextRenderer, n *Node) string {
+  parts := make([]string, 0, len(n.Params))
+  for ix := range n.Params {
+    if p := r.Param(n, ix); p != "" {
+      parts = append(parts, p)
+    }
+  }
+  return strings.Join(parts, " ")
+}
+
+// {{citation|citation=text}} or {{citation|text}}
+func renderCitation(r *TextRenderer, n *Node) string {
+  if _, ok := n.NamedParams["citation"]; ok {
+    return r.Named(n, "citation")
+  }
+  return r.Param(n, 0)
+}
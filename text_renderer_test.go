@@ -0,0 +1,57 @@
+package wikimediaparser
+
+import (
+  "testing"
+)
+
+func TestTextRendererDefaults(t *testing.T) {
+  cases := map[string]string{
+    "{{w|Victor Hugo}}":                       "Victor Hugo",
+    "{{w|Victor Hugo|Hugo}}":                  "Hugo",
+    "{{lang|en|To be or not to be}}":          "To be or not to be",
+    "{{citation|citation=Aimer, c’est tout}}": "Aimer, c’est tout",
+    "{{Citation|Aimer}}":                      "Aimer",
+    "{{date|1|mars|2010}}":                    "1 mars 2010",
+    "a{{tab}}b":                               "a\tb",
+    "[[Victor Hugo|Hugo]] et [[Zola]]":        "Hugo et Zola",
+    "{{personnage|Le Marchand}}":              "Le Marchand",
+  }
+  for doc, expected := range cases {
+    assertEqual(t, "Rendering of "+doc, expected, RenderText(Parse(Tokenize(doc))))
+  }
+}
+
+func TestTextRendererRegistry(t *testing.T) {
+  r := NewTextRenderer()
+  r.Register("Personnage", func(r *TextRenderer, n *Node) string {
+    return r.Param(n, 0) + " :"
+  })
+  r.Fallback = nil
+
+  p := Parse(Tokenize("{{personnage|Arturo Ui}}{{smallcaps|x}}"))
+  assertEqual(t, "Registered renderer", "Arturo Ui :", r.Render(p))
+}
+
+func TestTextRendererWhitespace(t *testing.T) {
+  p := Parse(Tokenize("  Il y a  des vers<br />qui riment\n\n{{tab}}et d'autres\n"))
+
+  r := NewTextRenderer()
+  r.CollapseWhitespace = true
+  assertEqual(t, "Collapsed", "Il y a des vers\nqui riment\net d'autres", r.Render(p))
+
+  r.LineBreaks = SlashLineBreaks
+  assertEqual(t, "Slashed", "Il y a des vers / qui riment / et d'autres", r.Render(p))
+
+  r.LineBreaks = SpaceLineBreaks
+  assertEqual(t, "Spaced", "Il y a des vers qui riment et d'autres", r.Render(p))
+}
+
+func TestTextRendererEmphasis(t *testing.T) {
+  p := Parse(Tokenize("'''Aimer''' c'est ''tout'', l''''autre''''''' fois'''''"))
+
+  r := NewTextRenderer()
+  assertEqual(t, "Kept", "'''Aimer''' c'est ''tout'', l''''autre''''''' fois'''''", r.Render(p))
+
+  r.StripEmphasis = true
+  assertEqual(t, "Stripped", "Aimer c'est tout, l'autre'' fois", r.Render(p))
+}
@@ -2,7 +2,6 @@ package wikimediaparser
 
 import (
   "fmt"
-  "github.com/golang/glog"
   "strings"
   "unicode/utf8"
 )
@@ -39,9 +38,9 @@ func (l *lexer) emit(t token) {
   var it item
 
   if l.start > len(l.input) {
-    it = item{t, ""}
+    it = item{t, "", len(l.input)}
   } else {
-    it = item{t, l.input[l.start:l.pos]}
+    it = item{t, l.input[l.start:l.pos], l.start}
   }
 
   l.items <- it
@@ -119,6 +118,7 @@ func (l *lexer) errorf(format string, args ...interface{}) stateFn {
   l.items <- item{
     itemError,
     fmt.Sprintf(format, args...),
+    l.start,
   }
   return nil
 }
@@ -171,12 +171,10 @@ func lexText(l *lexer) stateFn {
 }
 
 func Tokenize(body string) tokens {
-  glog.V(2).Infoln("************* Starting Tokenization *************")
   ret := make([]item, 0)
   l := lex("", body)
   go l.run()
 
-
   var it item
   halt := false
   for !halt {
@@ -7,6 +7,7 @@ import (
 type item struct {
   Typ token
   Val string
+  Pos int // byte offset of the item in the source
 }
 
 type token int
@@ -0,0 +1,256 @@
+package wikimediaparser
+
+import (
+  "fmt"
+  "reflect"
+  "sort"
+  "strconv"
+  "strings"
+  "time"
+)
+
+// UnmarshalError lists everything that went wrong while decoding a node.
+// The fields that could be decoded are set anyway.
+//    - Missing are the required parameters absent from the node
+//    - Unknown are the node parameters no field asked for
+//    - Invalid are the parameters whose value could not be converted
+type UnmarshalError struct {
+  Template string
+  Missing  []string
+  Unknown  []string
+  Invalid  map[string]error
+}
+
+func (e *UnmarshalError) Error() string {
+  parts := make([]string, 0)
+  if len(e.Missing) > 0 {
+    parts = append(parts, "missing "+strings.Join(e.Missing, ", "))
+  }
+  if len(e.Unknown) > 0 {
+    parts = append(parts, "unknown "+strings.Join(e.Unknown, ", "))
+  }
+  keys := make([]string, 0, len(e.Invalid))
+  for k := range e.Invalid {
+    keys = append(keys, k)
+  }
+  sort.Strings(keys)
+  for _, k := range keys {
+    parts = append(parts, fmt.Sprintf("invalid %s: %s", k, e.Invalid[k]))
+  }
+  return fmt.Sprintf("%s: %s", e.Template, strings.Join(parts, "; "))
+}
+
+// fieldSpec is the parsed form of a wq struct tag
+type fieldSpec struct {
+  names    []string
+  required bool
+  sep      string
+  layout   string
+}
+
+// parseFieldTag reads tags like `wq:"titre,alias=title,required"`.
+// Options are alias=name (repeatable), required, sep=; for []string fields
+// and layout=2006-01-02 for time.Time fields. Positional parameters are
+// named #0, #1... and untagged fields are ignored.
+func parseFieldTag(f reflect.StructField) (fieldSpec, bool) {
+  tag := f.Tag.Get("wq")
+  if tag == "" || tag == "-" {
+    return fieldSpec{}, false
+  }
+  opts := strings.Split(tag, ",")
+  spec := fieldSpec{names: []string{opts[0]}, sep: ","}
+  for _, opt := range opts[1:] {
+    switch {
+    case opt == "required":
+      spec.required = true
+    case strings.HasPrefix(opt, "alias="):
+      spec.names = append(spec.names, opt[len("alias="):])
+    case strings.HasPrefix(opt, "sep="):
+      spec.sep = opt[len("sep="):]
+    case strings.HasPrefix(opt, "layout="):
+      spec.layout = opt[len("layout="):]
+    }
+  }
+  return spec, true
+}
+
+// structuralParams are the named parameters the parser uses for the node itself
+var structuralParams = map[nodeType][]string{
+  NodeTemplate:    {"name"},
+  NodeLink:        {"link"},
+  NodeELink:       {"link"},
+  NodeTitle:       {"level", "title"},
+  NodePlaceholder: {"content"},
+}
+
+// Unmarshal fills the wq tagged fields of the struct pointed to by v with the
+// parameters of node. Supported field types are string, integers, floats,
+// bool, time.Time, []string, Nodes and Node (the first node of the parameter).
+// Empty parameters are considered missing. The returned *UnmarshalError also
+// reports the unknown parameters: callers that do not care can check Missing
+// and Invalid only.
+func Unmarshal(node Node, v interface{}) error {
+  rv := reflect.ValueOf(v)
+  if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+    return fmt.Errorf("Unmarshal expects a non nil pointer to a struct, got %T", v)
+  }
+  rv = rv.Elem()
+
+  uerr := &UnmarshalError{Template: strings.TrimSpace(node.StringParam("name")), Invalid: make(map[string]error)}
+  if node.Typ != NodeTemplate {
+    uerr.Template = node.Typ.String()
+  }
+  used := make(map[string]bool)
+
+  for ix := 0; ix < rv.NumField(); ix++ {
+    field := rv.Type().Field(ix)
+    spec, ok := parseFieldTag(field)
+    if !ok {
+      continue
+    }
+    if !rv.Field(ix).CanSet() {
+      return fmt.Errorf("Unmarshal can not set the unexported field %s of %s", field.Name, rv.Type())
+    }
+    name, value, found := lookupParam(node, spec.names)
+    if found {
+      used[name] = true
+    }
+    if !found || strings.TrimSpace(value.StringRepresentation()) == "" {
+      if spec.required {
+        uerr.Missing = append(uerr.Missing, spec.names[0])
+      }
+      continue
+    }
+    if err := setField(rv.Field(ix), value, spec); err != nil {
+      uerr.Invalid[name] = err
+    }
+  }
+
+  uerr.Unknown = unusedParams(&node, used)
+
+  if len(uerr.Missing) > 0 || len(uerr.Unknown) > 0 || len(uerr.Invalid) > 0 {
+    return uerr
+  }
+  return nil
+}
+
+// unusedParams lists the parameters of n missing from used, except the
+// structural ones and the blank positional parameters
+func unusedParams(n *Node, used map[string]bool) []string {
+  ret := make([]string, 0)
+  structural := make(map[string]bool)
+  for _, k := range structuralParams[n.Typ] {
+    structural[k] = true
+  }
+  for _, k := range n.namedKeys() {
+    if !used[k] && !structural[k] {
+      ret = append(ret, k)
+    }
+  }
+  for ix, p := range n.Params {
+    k := fmt.Sprintf("#%d", ix)
+    if !used[k] && strings.TrimSpace(p.StringRepresentation()) != "" {
+      ret = append(ret, k)
+    }
+  }
+  return ret
+}
+
+// FirstParam returns the first parameter of node present among names, #0, #1...
+// being the positional parameters
+func FirstParam(node Node, names ...string) (Nodes, bool) {
+  _, v, ok := lookupParam(node, names)
+  return v, ok
+}
+
+// lookupParam returns the first parameter present among names
+func lookupParam(node Node, names []string) (string, Nodes, bool) {
+  for _, name := range names {
+    if strings.HasPrefix(name, "#") {
+      ix, err := strconv.Atoi(name[1:])
+      if err == nil && ix >= 0 && ix < len(node.Params) {
+        return name, node.Params[ix], true
+      }
+      continue
+    }
+    if v, ok := node.NamedParams[name]; ok {
+      return name, v, true
+    }
+  }
+  return "", nil, false
+}
+
+var (
+  nodesType      = reflect.TypeOf(Nodes{})
+  singleNodeType = reflect.TypeOf(Node{})
+  timeType       = reflect.TypeOf(time.Time{})
+)
+
+func setField(f reflect.Value, value Nodes, spec fieldSpec) error {
+  switch f.Type() {
+  case nodesType:
+    f.Set(reflect.ValueOf(value))
+    return nil
+  case singleNodeType:
+    f.Set(reflect.ValueOf(trimNodes(value)[0]))
+    return nil
+  case timeType:
+    s := strings.TrimSpace(RenderText(value))
+    var t time.Time
+    var err error
+    if spec.layout != "" {
+      t, err = time.Parse(spec.layout, s)
+    } else {
+      t, err = ParseDate(s)
+    }
+    if err != nil {
+      return err
+    }
+    f.Set(reflect.ValueOf(t))
+    return nil
+  }
+
+  s := strings.TrimSpace(value.StringRepresentation())
+  switch f.Kind() {
+  case reflect.String:
+    f.SetString(s)
+  case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+    i, err := strconv.ParseInt(s, 10, f.Type().Bits())
+    if err != nil {
+      return err
+    }
+    f.SetInt(i)
+  case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+    i, err := strconv.ParseUint(s, 10, f.Type().Bits())
+    if err != nil {
+      return err
+    }
+    f.SetUint(i)
+  case reflect.Float32, reflect.Float64:
+    x, err := strconv.ParseFloat(strings.Replace(s, ",", ".", 1), f.Type().Bits())
+    if err != nil {
+      return err
+    }
+    f.SetFloat(x)
+  case reflect.Bool:
+    b, err := strconv.ParseBool(s)
+    if err != nil {
+      return err
+    }
+    f.SetBool(b)
+  case reflect.Slice:
+    if f.Type().Elem().Kind() != reflect.String {
+      return fmt.Errorf("unsupported field type %s", f.Type())
+    }
+    items := make([]string, 0)
+    for _, item := range strings.Split(s, spec.sep) {
+      if item = strings.TrimSpace(item); item != "" {
+        items = append(items, item)
+      }
+    }
+    f.Set(reflect.ValueOf(items).Convert(f.Type()))
+  default:
+    return fmt.Errorf("unsupported field type %s", f.Type())
+  }
+  return nil
+}
@@ -0,0 +1,87 @@
+package wikimediaparser
+
+import (
+  "testing"
+  "time"
+)
+
+type refLivre struct {
+  Titre   string    `wq:"titre,alias=title,required"`
+  Auteur  Nodes     `wq:"auteur,alias=author,required"`
+  Annee   int       `wq:"année,alias=year"`
+  Date    time.Time `wq:"date"`
+  Sujets  []string  `wq:"sujets,sep=;"`
+  Premier string    `wq:"#0"`
+  Ignored string
+}
+
+func TestUnmarshal(t *testing.T) {
+  doc := "{{Réf Livre|Premier|title=Éléments de philosophie|auteur=[[Alain]]|année=1980|date={{date|1|mars|2010}}|sujets=amour; vie}}"
+  var ref refLivre
+  err := Unmarshal(Parse(Tokenize(doc))[0], &ref)
+  if err != nil {
+    t.Fatal(err)
+  }
+  assertEqual(t, "Alias", "Éléments de philosophie", ref.Titre)
+  assertEqual(t, "Nodes field", NodeLink, ref.Auteur[0].Typ)
+  assertEqual(t, "Int field", 1980, ref.Annee)
+  assertEqual(t, "Date field", "2010-03-01", ref.Date.Format("2006-01-02"))
+  assertEqual(t, "Slice field", 2, len(ref.Sujets))
+  assertEqual(t, "Slice item", "vie", ref.Sujets[1])
+  assertEqual(t, "Positional field", "Premier", ref.Premier)
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+  doc := "{{Réf Livre|titre=Et si c'était vrai...|année=deux mille|éditeur=Pocket}}"
+  var ref refLivre
+  err := Unmarshal(Parse(Tokenize(doc))[0], &ref)
+
+  uerr, ok := err.(*UnmarshalError)
+  if !ok {
+    t.Fatalf("Expected an UnmarshalError, got %v", err)
+  }
+  assertEqual(t, "Decoded anyway", "Et si c'était vrai...", ref.Titre)
+  assertEqual(t, "Missing", "auteur", uerr.Missing[0])
+  assertEqual(t, "Unknown", "éditeur", uerr.Unknown[0])
+  assertEqual(t, "Invalid", true, uerr.Invalid["année"] != nil)
+  assertEqual(t, "Message", "Réf Livre: missing auteur; unknown éditeur; invalid année: strconv.ParseInt: parsing \"deux mille\": invalid syntax", err.Error())
+}
+
+func TestUnmarshalTarget(t *testing.T) {
+  var ref refLivre
+  if Unmarshal(EmptyNode(), ref) == nil {
+    t.Errorf("Expected an error when unmarshaling into a non pointer")
+  }
+}
+
+type unexportedRef struct {
+  titre string `wq:"titre"`
+}
+
+func TestUnmarshalUnexported(t *testing.T) {
+  var ref unexportedRef
+  err := Unmarshal(Parse(Tokenize("{{Réf Livre|titre=Pensées}}"))[0], &ref)
+  if err == nil {
+    t.Fatal("Expected an error for an unexported field")
+  }
+  assertEqual(t, "Message", "Unmarshal can not set the unexported field titre of wikimediaparser.unexportedRef", err.Error())
+}
+
+func TestParseDate(t *testing.T) {
+  cases := map[string]string{
+    "1980":          "1980-01-01",
+    "1er mars 2010": "2010-03-01",
+    "14 août 1956":  "1956-08-14",
+    "March 1, 2010": "2010-03-01",
+    "2010-03-01":    "2010-03-01",
+    "décembre 1898": "1898-12-01",
+  }
+  for s, expected := range cases {
+    d, err := ParseDate(s)
+    if err != nil {
+      t.Errorf("Unexpected error for %q: %s", s, err)
+      continue
+    }
+    assertEqual(t, "Date "+s, expected, d.Format("2006-01-02"))
+  }
+}
@@ -0,0 +1,159 @@
+package wikimediaparser
+
+import (
+  "fmt"
+  "sort"
+  "strings"
+)
+
+// Step locates a node inside its parent
+//    - Named is the named parameter holding the node (empty for positional parameters and top-level nodes)
+//    - Param is the positional parameter index (-1 for named parameters and top-level nodes)
+//    - Index is the position of the node in the Nodes list (-1 when the step designates the parameter itself)
+type Step struct {
+  Named string
+  Param int
+  Index int
+}
+
+func (s Step) String() string {
+  switch {
+  case s.Index < 0 && s.Named != "":
+    return s.Named
+  case s.Index < 0:
+    return fmt.Sprintf("#%d", s.Param)
+  case s.Named != "":
+    return fmt.Sprintf("%s/%d", s.Named, s.Index)
+  case s.Param >= 0:
+    return fmt.Sprintf("#%d/%d", s.Param, s.Index)
+  }
+  return fmt.Sprintf("%d", s.Index)
+}
+
+// Path is the list of steps from the top-level Nodes down to a node
+type Path []Step
+
+func (p Path) String() string {
+  parts := make([]string, 0, len(p))
+  for _, s := range p {
+    parts = append(parts, s.String())
+  }
+  return "/" + strings.Join(parts, "/")
+}
+
+func (p Path) child(s Step) Path {
+  ret := make(Path, len(p), len(p)+1)
+  copy(ret, p)
+  return append(ret, s)
+}
+
+// A Visitor's Visit method is invoked for each node encountered by Walk.
+// If the result visitor w is not nil, Walk visits each of the children
+// of node with the visitor w, followed by a call of w.Visit(nil, parent, path).
+type Visitor interface {
+  Visit(node *Node, parent *Node, path Path) (w Visitor)
+}
+
+// Walk traverses the nodes in depth-first order. Named parameters are visited
+// first, sorted by key, then positional parameters.
+// The nodes are handed out by pointer and can be modified in place.
+func Walk(ns Nodes, v Visitor) {
+  walkNodes(ns, v, nil, nil, Step{Param: -1})
+}
+
+func walkNodes(ns Nodes, v Visitor, parent *Node, path Path, s Step) {
+  for ix := range ns {
+    s.Index = ix
+    walkNode(&ns[ix], v, parent, path.child(s))
+  }
+}
+
+func walkNode(n *Node, v Visitor, parent *Node, path Path) {
+  if v = v.Visit(n, parent, path); v == nil {
+    return
+  }
+  for _, k := range n.namedKeys() {
+    walkNodes(n.NamedParams[k], v, n, path, Step{Named: k, Param: -1})
+  }
+  for ix, param := range n.Params {
+    walkNodes(param, v, n, path, Step{Param: ix})
+  }
+  v.Visit(nil, parent, path)
+}
+
+// namedKeys returns the named parameter keys in a stable order
+func (n *Node) namedKeys() []string {
+  keys := make([]string, 0, len(n.NamedParams))
+  for k := range n.NamedParams {
+    keys = append(keys, k)
+  }
+  sort.Strings(keys)
+  return keys
+}
+
+type inspector func(*Node, *Node, Path) bool
+
+func (f inspector) Visit(node *Node, parent *Node, path Path) Visitor {
+  if f(node, parent, path) {
+    return f
+  }
+  return nil
+}
+
+// Inspect traverses the nodes in depth-first order: it starts by calling
+// f(node, parent, path); if f returns true, Inspect invokes f recursively
+// for each of the children of node, followed by a call of f(nil, parent, path).
+func Inspect(ns Nodes, f func(node *Node, parent *Node, path Path) bool) {
+  Walk(ns, inspector(f))
+}
+
+// Flatten returns every node of the tree in walk order
+func Flatten(ns Nodes) Nodes {
+  ret := make(Nodes, 0, len(ns))
+  Inspect(ns, func(n *Node, _ *Node, _ Path) bool {
+    if n != nil {
+      ret = append(ret, *n)
+    }
+    return true
+  })
+  return ret
+}
+
+// TransformFunc is called by Transform once the children of a node have been
+// transformed. The returned Nodes replace the node in its parent list: return
+// Nodes{n} to keep it, an empty list to delete it, or several nodes to expand it.
+type TransformFunc func(n Node, parent *Node, path Path) Nodes
+
+// Transform rebuilds the tree bottom-up through f. The input nodes are left untouched.
+func Transform(ns Nodes, f TransformFunc) Nodes {
+  return transformNodes(ns, f, nil, nil, Step{Param: -1})
+}
+
+func transformNodes(ns Nodes, f TransformFunc, parent *Node, path Path, s Step) Nodes {
+  ret := make(Nodes, 0, len(ns))
+  for ix, n := range ns {
+    s.Index = ix
+    p := path.child(s)
+    n = transformChildren(n, f, p)
+    ret = append(ret, f(n, parent, p)...)
+  }
+  return ret
+}
+
+func transformChildren(n Node, f TransformFunc, path Path) Node {
+  ret := n
+  if n.NamedParams != nil {
+    ret.NamedParams = make(map[string]Nodes, len(n.NamedParams))
+  }
+  if n.Params != nil {
+    ret.Params = make([]Nodes, 0, len(n.Params))
+  }
+  // children see the original node as their parent
+  for _, k := range n.namedKeys() {
+    ret.NamedParams[k] = transformNodes(n.NamedParams[k], f, &n, path, Step{Named: k, Param: -1})
+  }
+  for ix, param := range n.Params {
+    ret.Params = append(ret.Params, transformNodes(param, f, &n, path, Step{Param: ix}))
+  }
+  return ret
+}
@@ -0,0 +1,74 @@
+package wikimediaparser
+
+import (
+  "testing"
+)
+
+func TestInspectNested(t *testing.T) {
+  doc := "{{début cadre|{{citation|Nested}}}}"
+  p := Parse(Tokenize(doc))
+
+  found := ""
+  var parentName string
+  Inspect(p, func(n *Node, parent *Node, path Path) bool {
+    if n != nil && n.Typ == NodeTemplate && n.StringParam("name") == "citation" {
+      found = path.String()
+      parentName = parent.StringParam("name")
+    }
+    return true
+  })
+  assertEqual(t, "Nested template path", "/0/#0/0", found)
+  assertEqual(t, "Nested template parent", "début cadre", parentName)
+}
+
+func TestInspectSkipChildren(t *testing.T) {
+  doc := "{{Réf Livre|auteur=[[Alain]]}} [[Amour]]"
+  p := Parse(Tokenize(doc))
+
+  links := 0
+  Inspect(p, func(n *Node, parent *Node, path Path) bool {
+    if n == nil {
+      return false
+    }
+    if n.Typ == NodeLink {
+      links += 1
+    }
+    return n.Typ != NodeTemplate
+  })
+  assertEqual(t, "Links outside templates", 1, links)
+}
+
+func TestFlatten(t *testing.T) {
+  p := Parse(Tokenize("{{w|Victor Hugo}}"))
+  // template, name text, then "Victor", " " and "Hugo"
+  assertEqual(t, "Flattened node count", 5, len(Flatten(p)))
+}
+
+func TestTransformDelete(t *testing.T) {
+  doc := "Hello {{tab}}[[world]]"
+  p := Parse(Tokenize(doc))
+
+  out := Transform(p, func(n Node, parent *Node, path Path) Nodes {
+    if n.Typ == NodeTemplate {
+      return Nodes{}
+    }
+    return Nodes{n}
+  })
+  assertEqual(t, "Node count", 3, len(out))
+  assertEqual(t, "Rendering", "Hello world", out.StringRepresentation())
+  assertEqual(t, "Original untouched", 4, len(p))
+}
+
+func TestTransformReplaceNested(t *testing.T) {
+  doc := "{{citation|citation=[[Victor Hugo|Hugo]]}}"
+  p := Parse(Tokenize(doc))
+
+  out := Transform(p, func(n Node, parent *Node, path Path) Nodes {
+    if n.Typ == NodeLink {
+      return Nodes{Node{Typ: NodeText, Val: n.StringParamOrEmpty("link")}}
+    }
+    return Nodes{n}
+  })
+  assertEqual(t, "Replaced link", "Victor Hugo", out[0].StringParamOrEmpty("citation"))
+  assertEqual(t, "Original link", "Hugo", p[0].StringParamOrEmpty("citation"))
+}
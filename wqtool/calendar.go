@@ -0,0 +1,76 @@
+package main
+
+import (
+  "bufio"
+  "flag"
+  "fmt"
+  "github.com/golang/glog"
+  . "github.com/octplane/wikiquote-parser"
+  "github.com/octplane/wikiquote-parser/quotes"
+  "os"
+)
+
+// runCalendar extracts the quotes of the pages and prints the days they were
+// the quote of the day, as JSON or iCalendar
+func runCalendar(args []string) int {
+  fs := flag.NewFlagSet("calendar", flag.ExitOnError)
+  source := addPageSourceFlags(fs, "extract")
+  profileFile := fs.String("profile", "", "JSON language profile, see profiles/, the built in fr profile by default")
+  format := fs.String("format", "json", "output format: json or ics")
+  opts := ParseOptions{}
+  fs.IntVar(&opts.MaxSteps, "max-steps", 5000000, "tokens the parser may consume on a page, 0 for no bound")
+  fs.Parse(args)
+
+  if *format != "json" && *format != "ics" {
+    fmt.Fprintf(os.Stderr, "calendar: unknown format %q\n", *format)
+    return 2
+  }
+
+  profile := quotes.Profiles["fr"]
+  if *profileFile != "" {
+    fi, err := os.Open(*profileFile)
+    if err != nil {
+      fmt.Fprintf(os.Stderr, "calendar: %s\n", err)
+      return 2
+    }
+    profile, err = quotes.LoadProfile(fi)
+    fi.Close()
+    if err != nil {
+      fmt.Fprintf(os.Stderr, "calendar: %s: %s\n", *profileFile, err)
+      return 2
+    }
+  }
+
+  calendar := quotes.NewCalendar()
+  err := source.each(fs.Args(), func(p page) {
+    nodes, _, err := ParseChecked(p.Text, opts)
+    if err != nil {
+      glog.Warningf("Skipping %s: %s", p.Title, err)
+      return
+    }
+    found, rejections := profile.Extract(quotes.Page{ID: p.ID, Title: p.Title}, nodes)
+    for _, r := range rejections {
+      glog.V(2).Infoln(r.String())
+    }
+    for _, q := range found {
+      calendar.Add(q)
+    }
+  })
+  if err != nil {
+    fmt.Fprintf(os.Stderr, "calendar: %s\n", err)
+    return 2
+  }
+
+  w := bufio.NewWriter(os.Stdout)
+  defer w.Flush()
+  if *format == "ics" {
+    err = calendar.WriteICS(w)
+  } else {
+    err = calendar.WriteJSON(w)
+  }
+  if err != nil {
+    fmt.Fprintf(os.Stderr, "calendar: %s\n", err)
+    return 2
+  }
+  return 0
+}
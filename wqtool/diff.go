@@ -0,0 +1,47 @@
+package main
+
+import (
+  "flag"
+  "fmt"
+  . "github.com/octplane/wikiquote-parser"
+  "io/ioutil"
+  "os"
+)
+
+// runDiff compares the trees of two wikitext files, typically two revisions
+// of the same page. Like diff, it exits with 1 when they differ.
+func runDiff(args []string) int {
+  fs := flag.NewFlagSet("diff", flag.ExitOnError)
+  spans := fs.Bool("spans", false, "also compare the node positions")
+  whitespace := fs.Bool("whitespace", false, "also compare the whitespace")
+  opts := addParseFlags(fs)
+  fs.Parse(args)
+
+  if fs.NArg() != 2 {
+    fmt.Fprintln(os.Stderr, "diff: expects two files")
+    fs.Usage()
+    return 2
+  }
+
+  trees := make([]Nodes, 0, 2)
+  for _, f := range fs.Args() {
+    text, err := ioutil.ReadFile(f)
+    if err != nil {
+      fmt.Fprintf(os.Stderr, "diff: %s\n", err)
+      return 2
+    }
+    nodes, _, err := ParseChecked(string(text), *opts)
+    if err != nil {
+      fmt.Fprintf(os.Stderr, "diff: %s: %s\n", f, err)
+      return 2
+    }
+    trees = append(trees, nodes)
+  }
+
+  diffs := Diff(trees[0], trees[1], EqualOptions{IgnoreSpans: !*spans, IgnoreWhitespace: !*whitespace})
+  if len(diffs) == 0 {
+    return 0
+  }
+  fmt.Println(diffs.String())
+  return 1
+}
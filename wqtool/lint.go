@@ -0,0 +1,64 @@
+package main
+
+import (
+  "bufio"
+  "encoding/json"
+  "flag"
+  "fmt"
+  . "github.com/octplane/wikiquote-parser"
+  "os"
+)
+
+// runLint reports the markup problems of every page, at or above the
+// severity threshold. The json format writes one diagnostic object per line.
+// It exits with 1 when something was reported, so that it can gate a CI job.
+func runLint(args []string) int {
+  fs := flag.NewFlagSet("lint", flag.ExitOnError)
+  source := addPageSourceFlags(fs, "lint")
+  format := fs.String("format", "text", "output format: text or json")
+  threshold := fs.String("severity", "warning", "lowest severity reported: info, warning or error")
+  opts := addParseFlags(fs)
+  fs.Parse(args)
+
+  min, err := ParseSeverity(*threshold)
+  if err != nil {
+    fmt.Fprintf(os.Stderr, "lint: %s\n", err)
+    return 2
+  }
+  if *format != "text" && *format != "json" {
+    fmt.Fprintf(os.Stderr, "lint: unknown format %q\n", *format)
+    return 2
+  }
+
+  w := bufio.NewWriter(os.Stdout)
+  defer w.Flush()
+  enc := json.NewEncoder(w)
+
+  count := 0
+  err = source.each(fs.Args(), func(p page) {
+    reported := make(Diagnostics, 0)
+    for _, d := range LintWithOptions(p.Text, *opts) {
+      if d.Severity >= min {
+        d.Page = p.Title
+        reported = append(reported, d)
+      }
+    }
+    count += len(reported)
+    if *format == "text" {
+      reported.WriteText(w)
+      return
+    }
+    for _, d := range reported {
+      enc.Encode(d)
+    }
+  })
+  if err != nil {
+    fmt.Fprintf(os.Stderr, "lint: %s\n", err)
+    return 2
+  }
+
+  if count > 0 {
+    return 1
+  }
+  return 0
+}
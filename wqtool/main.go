@@ -0,0 +1,61 @@
+package main
+
+// wqtool gathers the analysis commands working on wikitext files and
+// MediaWiki XML dumps:
+//
+//    wqtool [glog flags] <command> [command flags] [arguments]
+
+import (
+  "flag"
+  "fmt"
+  "github.com/golang/glog"
+  "os"
+)
+
+type command struct {
+  name  string
+  usage string
+  run   func(args []string) int
+}
+
+var commands = []command{
+  {"calendar", "calendar [-dump file.xml] [-ns list] [-exclude-ns list] [-profile file.json] [-format json|ics] [files]: print the quotes of the day by day", runCalendar},
+  {"lint", "lint [-dump file.xml] [-ns list] [-exclude-ns list] [-format text|json] [-severity warning] [-max-steps n] [-timeout d] [files]: report markup problems", runLint},
+  {"page", "page -dump file.xml.bz2 [-index file.txt.bz2] <titles>: print pages of a multistream dump", runPage},
+  {"quarantine", "quarantine -dump file.xml [-max-steps n] [-timeout d] [-trace] [-save dir] <file.quarantine>: parse again the pages quarantined by an extraction", runQuarantine},
+  {"select", "select [-dump file.xml] [-ns list] [-exclude-ns list] [-max-steps n] [-timeout d] <selector> [files]: print the nodes matching the selector", runSelect},
+  {"diff", "diff [-spans] [-whitespace] [-max-steps n] [-timeout d] <old> <new>: print the differences between the trees of two wikitext files", runDiff},
+  {"stats", "stats [-dump file.xml] [-ns list] [-exclude-ns list] [-format json|csv] [-max-steps n] [-timeout d] [files]: count templates, parameters, link namespaces, headings, tags and parser recoveries", runStats},
+  {"validate", "validate [-dump file.xml] [-ns list] [-exclude-ns list] [-schema file.json] [files]: check template calls against their schema", runValidate},
+}
+
+func usage() {
+  fmt.Fprintf(os.Stderr, "Usage: %s [flags] <command> [command flags] [arguments]\n\nCommands:\n", os.Args[0])
+  for _, c := range commands {
+    fmt.Fprintf(os.Stderr, "  %s\n", c.usage)
+  }
+  fmt.Fprintf(os.Stderr, "\nFlags:\n")
+  flag.PrintDefaults()
+}
+
+func main() {
+  flag.Usage = usage
+  flag.Parse()
+
+  if flag.NArg() < 1 {
+    usage()
+    os.Exit(2)
+  }
+
+  for _, c := range commands {
+    if c.name == flag.Arg(0) {
+      code := c.run(flag.Args()[1:])
+      glog.Flush()
+      os.Exit(code)
+    }
+  }
+
+  fmt.Fprintf(os.Stderr, "Unknown command %q\n", flag.Arg(0))
+  usage()
+  os.Exit(2)
+}
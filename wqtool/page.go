@@ -0,0 +1,45 @@
+package main
+
+import (
+  "flag"
+  "fmt"
+  "github.com/octplane/wikiquote-parser/dump"
+  "os"
+)
+
+// runPage prints the wikitext of pages of a multistream dump, found through
+// its index without decompressing the whole dump
+func runPage(args []string) int {
+  fs := flag.NewFlagSet("page", flag.ExitOnError)
+  dumpFile := fs.String("dump", "", "multistream .xml.bz2 dump")
+  indexFile := fs.String("index", "", "multistream index, guessed from the dump name by default")
+  fs.Parse(args)
+
+  if *dumpFile == "" || fs.NArg() < 1 {
+    fmt.Fprintln(os.Stderr, "page: expects -dump and at least one title")
+    fs.Usage()
+    return 2
+  }
+  if *indexFile == "" {
+    *indexFile = dump.IndexPath(*dumpFile)
+  }
+
+  m, err := dump.OpenMultistream(*dumpFile, *indexFile)
+  if err != nil {
+    fmt.Fprintf(os.Stderr, "page: %s\n", err)
+    return 2
+  }
+  defer m.Close()
+
+  code := 0
+  for _, title := range fs.Args() {
+    p, err := m.Page(title)
+    if err != nil {
+      fmt.Fprintf(os.Stderr, "page: %s: %s\n", title, err)
+      code = 1
+      continue
+    }
+    fmt.Println(p.Revision.Text)
+  }
+  return code
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+  "flag"
+  "github.com/golang/glog"
+  . "github.com/octplane/wikiquote-parser"
+  "github.com/octplane/wikiquote-parser/dump"
+  "io"
+  "io/ioutil"
+  "os"
+  "strings"
+  "time"
+)
+
+// page is the unit of work of every command: a dump page or a wikitext file
+type page struct {
+  Title string
+  ID    int
+  Text  string
+}
+
+// pageSource holds the flags choosing the pages a command works on, and the
+// site info of the dump once it is opened
+type pageSource struct {
+  dump    *string
+  include *string
+  exclude *string
+  workers *int
+
+  siteInfo *dump.SiteInfo
+}
+
+func addPageSourceFlags(fs *flag.FlagSet, what string) *pageSource {
+  return &pageSource{
+    dump:    fs.String("dump", "", "MediaWiki XML dump (.xml or .xml.bz2) to "+what+" instead of wikitext files"),
+    include: fs.String("ns", "", "comma separated namespaces of the dump pages to keep, by number or name, all by default"),
+    exclude: fs.String("exclude-ns", "", "comma separated namespaces of the dump pages to skip"),
+    workers: fs.Int("workers", 0, "goroutines decompressing a multistream dump with its index, one per CPU by default"),
+  }
+}
+
+// addParseFlags binds the budget of the parse of each page, so that a page
+// breaking the parser cannot hang a command running over a whole dump
+func addParseFlags(fs *flag.FlagSet) *ParseOptions {
+  opts := &ParseOptions{}
+  fs.IntVar(&opts.MaxSteps, "max-steps", 5000000, "tokens the parser may consume on a page, 0 for no bound")
+  fs.DurationVar(&opts.Timeout, "timeout", 30*time.Second, "time the parser may spend on a page, 0 for no bound")
+  return opts
+}
+
+// each calls f for every page of the dump, or for every wikitext file
+// when no dump is given. Standard input is read when there is no file either.
+func (s *pageSource) each(files []string, f func(p page)) error {
+  if *s.dump != "" {
+    return s.eachDumpPage(f)
+  }
+
+  if len(files) == 0 {
+    text, err := ioutil.ReadAll(os.Stdin)
+    if err != nil {
+      return err
+    }
+    f(page{Title: "-", Text: string(text)})
+    return nil
+  }
+
+  for _, fname := range files {
+    text, err := ioutil.ReadFile(fname)
+    if err != nil {
+      return err
+    }
+    f(page{Title: fname, Text: string(text)})
+  }
+  return nil
+}
+
+// namespaceName resolves a link prefix with the site info of the dump, with
+// the canonical namespace names only for wikitext files
+func (s *pageSource) namespaceName(prefix string) (string, bool) {
+  return s.siteInfo.NamespaceName(prefix)
+}
+
+func (s *pageSource) filter(si *dump.SiteInfo) (*dump.NamespaceFilter, error) {
+  s.siteInfo = si
+  return dump.NewNamespaceFilter(si, dump.ParseNamespaceList(*s.include), dump.ParseNamespaceList(*s.exclude))
+}
+
+func (s *pageSource) eachDumpPage(f func(p page)) error {
+  dumpFile := *s.dump
+  // multistream dumps with their index are decompressed on all the CPUs
+  if _, err := os.Stat(dump.IndexPath(dumpFile)); err == nil && strings.HasSuffix(dumpFile, ".xml.bz2") {
+    m, err := dump.OpenMultistream(dumpFile, dump.IndexPath(dumpFile))
+    if err != nil {
+      return err
+    }
+    defer m.Close()
+    si, err := m.SiteInfo()
+    if err != nil {
+      return err
+    }
+    filter, err := s.filter(si)
+    if err != nil {
+      return err
+    }
+    return m.Each(*s.workers, func(p *dump.Page) error {
+      visit(filter, p, f)
+      return nil
+    })
+  }
+
+  r, err := dump.Open(dumpFile)
+  if err != nil {
+    return err
+  }
+  defer r.Close()
+  filter, err := s.filter(r.SiteInfo())
+  if err != nil {
+    return err
+  }
+
+  for {
+    p, err := r.Next()
+    if err == io.EOF {
+      return nil
+    }
+    if err != nil {
+      return err
+    }
+    visit(filter, p, f)
+  }
+}
+
+func visit(filter *dump.NamespaceFilter, p *dump.Page, f func(p page)) {
+  if !filter.Match(p.NS) {
+    glog.V(3).Infof("Ignoring %s", p.Title)
+    return
+  }
+  glog.V(3).Infof("Entering %s", p.Title)
+  f(page{Title: p.Title, ID: p.ID, Text: p.Revision.Text})
+}
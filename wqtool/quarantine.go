@@ -0,0 +1,144 @@
+package main
+
+import (
+  "flag"
+  "fmt"
+  . "github.com/octplane/wikiquote-parser"
+  "github.com/octplane/wikiquote-parser/dump"
+  "github.com/octplane/wikiquote-parser/pipeline"
+  "io"
+  "io/ioutil"
+  "os"
+  "path/filepath"
+  "strconv"
+  "strings"
+  "time"
+)
+
+// runQuarantine parses again the pages quarantined by an extraction and
+// prints how each parse ends. It exits with 1 when some still fail.
+func runQuarantine(args []string) int {
+  fs := flag.NewFlagSet("quarantine", flag.ExitOnError)
+  dumpFile := fs.String("dump", "", "MediaWiki XML dump the pages were extracted from")
+  opts := ParseOptions{}
+  fs.IntVar(&opts.MaxSteps, "max-steps", 5000000, "tokens the parser may consume on a page, 0 for no bound")
+  fs.DurationVar(&opts.Timeout, "timeout", 30*time.Second, "time the parser may spend on a page, 0 for no bound")
+  trace := fs.Bool("trace", false, "print the parse events on the standard error")
+  save := fs.String("save", "", "directory where to write the wikitext of the pages, as <id>.wiki")
+  fs.Parse(args)
+
+  if *dumpFile == "" || fs.NArg() != 1 {
+    fmt.Fprintln(os.Stderr, "quarantine: expects -dump and a quarantine file")
+    fs.Usage()
+    return 2
+  }
+  if *trace {
+    opts.Trace = stderrLogger{}
+  }
+
+  quarantined, err := pipeline.ReadQuarantine(fs.Arg(0))
+  if err != nil {
+    fmt.Fprintf(os.Stderr, "quarantine: %s\n", err)
+    return 2
+  }
+
+  failed := 0
+  err = quarantinedPages(*dumpFile, quarantined, func(q pipeline.Quarantined, p *dump.Page) error {
+    if *save != "" {
+      fname := filepath.Join(*save, strconv.Itoa(p.ID)+".wiki")
+      if err := ioutil.WriteFile(fname, []byte(p.Revision.Text), 0644); err != nil {
+        return err
+      }
+    }
+    fmt.Printf("%d\t%s\n  quarantined: %s\n", p.ID, p.Title, q.Reason)
+    start := time.Now()
+    nodes, diagnostics, err := ParseChecked(p.Revision.Text, opts)
+    if err != nil {
+      failed += 1
+      fmt.Printf("  failed: %s\n", err)
+      return nil
+    }
+    fmt.Printf("  parsed: %d nodes, %d diagnostics in %s\n", len(nodes), len(diagnostics), time.Since(start))
+    return nil
+  })
+  if err != nil {
+    fmt.Fprintf(os.Stderr, "quarantine: %s\n", err)
+    return 2
+  }
+
+  if failed > 0 {
+    return 1
+  }
+  return 0
+}
+
+// quarantinedPages calls f with the dump page of every quarantined entry, in
+// the quarantine order. The pages are found through the index of multistream
+// dumps, by reading the whole dump otherwise.
+func quarantinedPages(dumpFile string, quarantined []pipeline.Quarantined, f func(q pipeline.Quarantined, p *dump.Page) error) error {
+  if _, err := os.Stat(dump.IndexPath(dumpFile)); err == nil && strings.HasSuffix(dumpFile, ".xml.bz2") {
+    m, err := dump.OpenMultistream(dumpFile, dump.IndexPath(dumpFile))
+    if err != nil {
+      return err
+    }
+    defer m.Close()
+    for _, q := range quarantined {
+      p, err := m.PageByID(q.ID)
+      if err != nil {
+        return fmt.Errorf("%d %s: %s", q.ID, q.Title, err)
+      }
+      if err := f(q, p); err != nil {
+        return err
+      }
+    }
+    return nil
+  }
+
+  pages := make(map[int]*dump.Page, len(quarantined))
+  for _, q := range quarantined {
+    pages[q.ID] = nil
+  }
+  r, err := dump.Open(dumpFile)
+  if err != nil {
+    return err
+  }
+  defer r.Close()
+  for {
+    p, err := r.Next()
+    if err == io.EOF {
+      break
+    }
+    if err != nil {
+      return err
+    }
+    if _, ok := pages[p.ID]; ok {
+      pages[p.ID] = p
+    }
+  }
+
+  for _, q := range quarantined {
+    if pages[q.ID] == nil {
+      return fmt.Errorf("%d %s: %s", q.ID, q.Title, dump.ErrNotFound)
+    }
+    if err := f(q, pages[q.ID]); err != nil {
+      return err
+    }
+  }
+  return nil
+}
+
+// stderrLogger prints the parse events for -trace
+type stderrLogger struct{}
+
+func (stderrLogger) print(level string, msg string, args []interface{}) {
+  line := level + " " + strings.TrimRight(msg, "\n")
+  for ix := 0; ix+1 < len(args); ix += 2 {
+    line += fmt.Sprintf(" %v=%v", args[ix], args[ix+1])
+  }
+  fmt.Fprintln(os.Stderr, line)
+}
+
+func (l stderrLogger) Debug(msg string, args ...interface{}) { l.print("DEBUG", msg, args) }
+func (l stderrLogger) Info(msg string, args ...interface{})  { l.print("INFO", msg, args) }
+func (l stderrLogger) Warn(msg string, args ...interface{})  { l.print("WARN", msg, args) }
+func (l stderrLogger) Error(msg string, args ...interface{}) { l.print("ERROR", msg, args) }
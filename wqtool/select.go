@@ -0,0 +1,60 @@
+package main
+
+import (
+  "bufio"
+  "flag"
+  "fmt"
+  . "github.com/octplane/wikiquote-parser"
+  "os"
+  "strings"
+)
+
+// runSelect prints one line per match: page title, path in the tree and text.
+// Like grep, it exits with 1 when nothing matched.
+func runSelect(args []string) int {
+  fs := flag.NewFlagSet("select", flag.ExitOnError)
+  source := addPageSourceFlags(fs, "query")
+  opts := addParseFlags(fs)
+  fs.Parse(args)
+
+  if fs.NArg() < 1 {
+    fmt.Fprintln(os.Stderr, "select: missing selector")
+    fs.Usage()
+    return 2
+  }
+
+  sel, err := CompileSelector(fs.Arg(0))
+  if err != nil {
+    fmt.Fprintf(os.Stderr, "select: %s\n", err)
+    return 2
+  }
+
+  w := bufio.NewWriter(os.Stdout)
+  defer w.Flush()
+
+  count := 0
+  err = source.each(fs.Args()[1:], func(p page) {
+    nodes, _, err := ParseChecked(p.Text, *opts)
+    if err != nil {
+      fmt.Fprintf(os.Stderr, "select: skipping %s: %s\n", p.Title, err)
+      return
+    }
+    for _, m := range sel.Select(nodes) {
+      count += 1
+      fmt.Fprintf(w, "%s\t%s\t%s\n", p.Title, m.Path, oneLine(m.StringRepresentation()))
+    }
+  })
+  if err != nil {
+    fmt.Fprintf(os.Stderr, "select: %s\n", err)
+    return 2
+  }
+
+  if count == 0 {
+    return 1
+  }
+  return 0
+}
+
+func oneLine(s string) string {
+  return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+  "bufio"
+  "flag"
+  "fmt"
+  . "github.com/octplane/wikiquote-parser"
+  "os"
+)
+
+// runStats aggregates the markup usage of all the pages and prints it as JSON or CSV
+func runStats(args []string) int {
+  fs := flag.NewFlagSet("stats", flag.ExitOnError)
+  source := addPageSourceFlags(fs, "analyse")
+  format := fs.String("format", "json", "output format: json or csv")
+  opts := addParseFlags(fs)
+  fs.Parse(args)
+
+  if *format != "json" && *format != "csv" {
+    fmt.Fprintf(os.Stderr, "stats: unknown format %q\n", *format)
+    return 2
+  }
+
+  stats := NewStats()
+  stats.Namespace = source.namespaceName
+  err := source.each(fs.Args(), func(p page) {
+    if err := stats.AddWithOptions(p.Text, *opts); err != nil {
+      fmt.Fprintf(os.Stderr, "stats: skipping %s: %s\n", p.Title, err)
+    }
+  })
+  if err != nil {
+    fmt.Fprintf(os.Stderr, "stats: %s\n", err)
+    return 2
+  }
+
+  w := bufio.NewWriter(os.Stdout)
+  defer w.Flush()
+  if *format == "csv" {
+    err = stats.WriteCSV(w)
+  } else {
+    err = stats.WriteJSON(w)
+  }
+  if err != nil {
+    fmt.Fprintf(os.Stderr, "stats: %s\n", err)
+    return 2
+  }
+  return 0
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+  "bufio"
+  "flag"
+  "fmt"
+  . "github.com/octplane/wikiquote-parser"
+  "os"
+)
+
+// runValidate checks the template calls against a schema file and prints
+// one line per violation. It exits with 1 when violations were found.
+func runValidate(args []string) int {
+  fs := flag.NewFlagSet("validate", flag.ExitOnError)
+  source := addPageSourceFlags(fs, "validate")
+  schemaFile := fs.String("schema", "schemas/frwikiquote.json", "JSON template schemas")
+  fs.Parse(args)
+
+  fi, err := os.Open(*schemaFile)
+  if err != nil {
+    fmt.Fprintf(os.Stderr, "validate: %s\n", err)
+    return 2
+  }
+  schemas, err := LoadSchemas(fi)
+  fi.Close()
+  if err != nil {
+    fmt.Fprintf(os.Stderr, "validate: %s: %s\n", *schemaFile, err)
+    return 2
+  }
+
+  w := bufio.NewWriter(os.Stdout)
+  defer w.Flush()
+
+  count := 0
+  err = source.each(fs.Args(), func(p page) {
+    for _, v := range schemas.ValidatePage(p.Title, p.Text) {
+      count += 1
+      fmt.Fprintln(w, v.String())
+    }
+  })
+  if err != nil {
+    fmt.Fprintf(os.Stderr, "validate: %s\n", err)
+    return 2
+  }
+
+  if count > 0 {
+    return 1
+  }
+  return 0
+}